@@ -1,6 +1,10 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Album struct {
 	api *API
@@ -13,47 +17,98 @@ func NewAlbum(api *API) *Album {
 
 // Info returns the information of an album by artist and album name.
 func (a Album) Info(params lastfm.AlbumInfoParams) (*lastfm.AlbumInfo, error) {
+	return a.InfoContext(context.Background(), params)
+}
+
+// InfoContext is like Info but uses the given context for the request.
+func (a Album) InfoContext(
+	ctx context.Context, params lastfm.AlbumInfoParams) (*lastfm.AlbumInfo, error) {
+
 	var res lastfm.AlbumInfo
-	return &res, a.api.Get(&res, AlbumGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetInfoMethod, params)
 }
 
 // InfoByMBID returns the information of an album by MBID.
 func (a Album) InfoByMBID(params lastfm.AlbumInfoMBIDParams) (*lastfm.AlbumInfo, error) {
+	return a.InfoByMBIDContext(context.Background(), params)
+}
+
+// InfoByMBIDContext is like InfoByMBID but uses the given context for the
+// request.
+func (a Album) InfoByMBIDContext(
+	ctx context.Context, params lastfm.AlbumInfoMBIDParams) (*lastfm.AlbumInfo, error) {
+
 	var res lastfm.AlbumInfo
-	return &res, a.api.Get(&res, AlbumGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetInfoMethod, params)
 }
 
 // UserInfo returns the information of an album for user by artist and album
 // name.
 func (a Album) UserInfo(params lastfm.AlbumUserInfoParams) (*lastfm.AlbumUserInfo, error) {
+	return a.UserInfoContext(context.Background(), params)
+}
+
+// UserInfoContext is like UserInfo but uses the given context for the request.
+func (a Album) UserInfoContext(
+	ctx context.Context, params lastfm.AlbumUserInfoParams) (*lastfm.AlbumUserInfo, error) {
+
 	var res lastfm.AlbumUserInfo
-	return &res, a.api.Get(&res, AlbumGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetInfoMethod, params)
 }
 
 // UserInfoByMBID returns the information of an album for user by MBID.
 func (a Album) UserInfoByMBID(
 	params lastfm.AlbumUserInfoMBIDParams) (*lastfm.AlbumUserInfo, error) {
+	return a.UserInfoByMBIDContext(context.Background(), params)
+}
+
+// UserInfoByMBIDContext is like UserInfoByMBID but uses the given context for
+// the request.
+func (a Album) UserInfoByMBIDContext(
+	ctx context.Context, params lastfm.AlbumUserInfoMBIDParams) (*lastfm.AlbumUserInfo, error) {
 
 	var res lastfm.AlbumUserInfo
-	return &res, a.api.Get(&res, AlbumGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetInfoMethod, params)
 }
 
 // UserTags returns the tags of an album for user by artist and album name.
 func (a Album) UserTags(params lastfm.AlbumTagsParams) (*lastfm.AlbumTags, error) {
+	return a.UserTagsContext(context.Background(), params)
+}
+
+// UserTagsContext is like UserTags but uses the given context for the request.
+func (a Album) UserTagsContext(
+	ctx context.Context, params lastfm.AlbumTagsParams) (*lastfm.AlbumTags, error) {
+
 	var res lastfm.AlbumTags
-	return &res, a.api.Get(&res, AlbumGetTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetTagsMethod, params)
 }
 
 // UserTagsByMBID returns the tags of an album for user by MBID.
 func (a Album) UserTagsByMBID(params lastfm.AlbumTagsMBIDParams) (*lastfm.AlbumTags, error) {
+	return a.UserTagsByMBIDContext(context.Background(), params)
+}
+
+// UserTagsByMBIDContext is like UserTagsByMBID but uses the given context for
+// the request.
+func (a Album) UserTagsByMBIDContext(
+	ctx context.Context, params lastfm.AlbumTagsMBIDParams) (*lastfm.AlbumTags, error) {
+
 	var res lastfm.AlbumTags
-	return &res, a.api.Get(&res, AlbumGetTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetTagsMethod, params)
 }
 
 // TopTags returns the top tags of an album by artist and album name.
 func (a Album) TopTags(params lastfm.AlbumTopTagsParams) (*lastfm.AlbumTopTags, error) {
+	return a.TopTagsContext(context.Background(), params)
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (a Album) TopTagsContext(
+	ctx context.Context, params lastfm.AlbumTopTagsParams) (*lastfm.AlbumTopTags, error) {
+
 	var res lastfm.AlbumTopTags
-	return &res, a.api.Get(&res, AlbumGetTopTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetTopTagsMethod, params)
 }
 
 // TopTagsByMBID returns the top tags of an album by MBID.
@@ -61,12 +116,27 @@ func (a Album) TopTags(params lastfm.AlbumTopTagsParams) (*lastfm.AlbumTopTags,
 // Deprecated: Fetching top tags by MBID doesn't seem to work. Use TopTags
 // instead.
 func (a Album) TopTagsByMBID(params lastfm.AlbumTopTagsMBIDParams) (*lastfm.AlbumTopTags, error) {
+	return a.TopTagsByMBIDContext(context.Background(), params)
+}
+
+// TopTagsByMBIDContext is like TopTagsByMBID but uses the given context for the
+// request.
+func (a Album) TopTagsByMBIDContext(
+	ctx context.Context, params lastfm.AlbumTopTagsMBIDParams) (*lastfm.AlbumTopTags, error) {
+
 	var res lastfm.AlbumTopTags
-	return &res, a.api.Get(&res, AlbumGetTopTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumGetTopTagsMethod, params)
 }
 
 // Search returns the results of an album search.
 func (a Album) Search(params lastfm.AlbumSearchParams) (*lastfm.AlbumSearchResult, error) {
+	return a.SearchContext(context.Background(), params)
+}
+
+// SearchContext is like Search but uses the given context for the request.
+func (a Album) SearchContext(
+	ctx context.Context, params lastfm.AlbumSearchParams) (*lastfm.AlbumSearchResult, error) {
+
 	var res lastfm.AlbumSearchResult
-	return &res, a.api.Get(&res, AlbumSearchMethod, params)
+	return &res, a.api.GetContext(ctx, &res, AlbumSearchMethod, params)
 }
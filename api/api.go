@@ -14,25 +14,56 @@
 //   - Build API URLs with query parameters.
 //   - Generate signatures for API requests using the API secret.
 //   - Make GET and POST requests to the API with automatic XML unmarshaling.
+//   - Request responses in JSON instead of XML with SetFormat(FormatJSON),
+//     decoded into the same types.
+//   - Iterate lazily over every page of paginated responses using the `All`
+//     methods, e.g. User.RecentTracksAll. Pages are only requested as the
+//     iterator advances, and a failed request is yielded as an error, after
+//     which iteration stops.
+//   - Cache responses of rarely changing methods, such as Artist.Info, in
+//     memory or on disk with SetCache. Signed requests are never cached, and
+//     writes such as track.love invalidate the related cached responses.
+//   - Coalesce identical unsigned GET requests made at the same time into a
+//     single HTTP request.
+//   - Add logging, metrics, tracing or fault injection around every API call
+//     with interceptors added by Use.
+//   - Log requests with log/slog using SetLogger. API keys, session keys,
+//     signatures, passwords and tokens are always redacted.
+//   - Trace calls and record metrics through the Tracer and Metrics
+//     interfaces, or export metrics to Prometheus with PrometheusMetrics.
+//   - Match Last.fm errors with errors.Is and sentinels such as
+//     ErrInvalidSessionKeyError, or classify them with IsAuthError,
+//     IsTransient and IsPermanent.
+//   - Inspect raw response bodies and headers for debugging with WithResponse
+//     and SetCaptureResponses.
+//   - Fail fast during sustained outages with a CircuitBreaker set by
+//     SetBreaker, which opens after too many transient failures and probes
+//     the API before closing again.
 //
 // Usage:
 //   - Create a new Client with your API key and optionally, your secret.
 //   - Use the Client to access specific API methods
 //   - Handle responses and errors using the provided types and utilities.
 //   - Customize the client with user agent and timeout settings.
+//   - Use SetService to talk to other Last.fm-compatible services, such as
+//     LibreFM. Each client can use a different service.
 //   - Use the `Request` method for general-purpose API requests.
+//   - Use the `Context` variants of each method to cancel requests or set
+//     deadlines.
 //
 // For more information about the Last.fm API, visit:
 // https://www.last.fm/api
 package api
 
 import (
+	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"maps"
 	"net/http"
 	"net/url"
@@ -82,11 +113,59 @@ type API struct {
 	APIKey string
 	// Secret is the Last.fm API secret used to sign requests.
 	Secret string
+	// Endpoint is the URL of the API endpoint requests are sent to. If empty,
+	// the package-level Endpoint is used.
+	Endpoint string
+	// AuthEndpoint is the URL users are sent to in order to authorize the
+	// application. If empty, lastfm.AuthURL is used.
+	AuthEndpoint string
 	// UserAgent is the user agent string sent with each request to the API.
 	UserAgent string
-	// Retries is the number of times to retry failed requests.
+	// Format is the format responses are requested in. Responses are decoded
+	// into the same types regardless of format. Defaults to FormatXML.
+	Format Format
+	// Retries is the maximum number of times to retry failed requests.
 	Retries uint
+	// RetryPolicy decides whether and when failed requests are retried. If
+	// nil, retryable failures are retried immediately.
+	RetryPolicy RetryPolicy
+	// OnRetry, if set, is called before each retry with the failed attempt
+	// and the delay before the next attempt is made.
+	OnRetry func(attempt RetryAttempt, delay time.Duration)
+	// Limiter, if set, limits the rate of requests made by the API client.
+	// Every attempt, including retries, waits on the limiter. Share the same
+	// Limiter between API clients that use the same API key.
+	Limiter Limiter
+	// Cache, if set, caches the responses of unsigned GET requests. Share the
+	// same Cache between API clients to share cached responses.
+	Cache *Cache
+	// Coalesce, if true, makes identical unsigned GET requests that are in
+	// flight at the same time share a single HTTP request. Each caller decodes
+	// its own copy of the response, or receives the same error. Enabled by
+	// default.
+	Coalesce bool
+	// Interceptors are called around every call to the API, in order. Add
+	// interceptors with Use.
+	Interceptors []Interceptor
+	// Logger, if set, logs every request attempt, with credentials redacted.
+	// Failed attempts are logged at the warn level, and successful attempts,
+	// request and response bodies at the debug level.
+	Logger *slog.Logger
+	// Tracer, if set, starts a span for every call to the API.
+	Tracer Tracer
+	// Metrics, if set, records the stats of every call to the API.
+	Metrics Metrics
+	// CaptureResponses, if true, attaches the raw responses of failed calls
+	// to the returned *LastFMError and *HTTPError. Use WithResponse to get
+	// the raw responses of successful calls. Responses of coalesced requests
+	// are always attached, as they're shared between callers.
+	CaptureResponses bool
+	// Breaker, if set, stops requests from being sent while it's open, so they
+	// fail fast with a *CircuitOpenError during sustained outages.
+	Breaker *CircuitBreaker
 	Client  HTTPClient
+
+	inflight flightGroup
 }
 
 // New returns a new instance of API with the given API key.
@@ -100,11 +179,13 @@ func NewWithTimeout(apiKey, secret string, timeout int) *API {
 	t := time.Duration(timeout) * time.Second
 
 	return &API{
-		APIKey:    apiKey,
-		Secret:    secret,
-		UserAgent: DefaultUserAgent,
-		Retries:   DefaultRetries,
-		Client:    &http.Client{Timeout: t},
+		APIKey:      apiKey,
+		Secret:      secret,
+		UserAgent:   DefaultUserAgent,
+		Retries:     DefaultRetries,
+		RetryPolicy: NewExponentialBackoff(),
+		Coalesce:    true,
+		Client:      &http.Client{Timeout: t},
 	}
 }
 
@@ -115,23 +196,61 @@ func NewKeyOnly(apiKey string) *API {
 	t := time.Duration(DefaultTimeout) * time.Second
 
 	return &API{
-		APIKey:    apiKey,
-		UserAgent: DefaultUserAgent,
-		Retries:   DefaultRetries,
-		Client:    &http.Client{Timeout: t},
+		APIKey:      apiKey,
+		UserAgent:   DefaultUserAgent,
+		Retries:     DefaultRetries,
+		RetryPolicy: NewExponentialBackoff(),
+		Coalesce:    true,
+		Client:      &http.Client{Timeout: t},
 	}
 }
 
+// SetService sets the API endpoint and authorization URL of the API client to
+// those of the given service, e.g. LibreFM.
+func (a *API) SetService(service Service) {
+	a.Endpoint = service.Endpoint
+	a.AuthEndpoint = service.AuthEndpoint
+}
+
 // SetUserAgent sets the user agent for the API client.
 func (a *API) SetUserAgent(userAgent string) {
 	a.UserAgent = userAgent
 }
 
+// SetFormat sets the format responses are requested in.
+func (a *API) SetFormat(format Format) {
+	a.Format = format
+}
+
 // SetRetries sets the number of retries for failed requests.
 func (a *API) SetRetries(retries uint) {
 	a.Retries = retries
 }
 
+// SetRetryPolicy sets the policy used to decide whether and when failed
+// requests are retried.
+func (a *API) SetRetryPolicy(policy RetryPolicy) {
+	a.RetryPolicy = policy
+}
+
+// SetLimiter sets the limiter used to limit the rate of requests made by the
+// API client. Pass nil to disable rate limiting.
+func (a *API) SetLimiter(limiter Limiter) {
+	a.Limiter = limiter
+}
+
+// SetCache sets the cache used to cache responses. Pass nil to disable
+// caching.
+func (a *API) SetCache(cache *Cache) {
+	a.Cache = cache
+}
+
+// SetCoalesce sets whether identical unsigned GET requests that are in flight
+// at the same time share a single HTTP request.
+func (a *API) SetCoalesce(coalesce bool) {
+	a.Coalesce = coalesce
+}
+
 // AuthURL returns the authorization URL for the Last.fm API. This method should
 // be used for web authentication if you set a callback URL when creating your
 // API account. Otherwise, use AuthCallbackURL and provide a custom callback
@@ -149,7 +268,8 @@ func (a *API) AuthURL() string {
 //
 // https://www.last.fm/api/webauth
 func (a *API) AuthCallbackURL(callbackURL string) string {
-	return AuthURL(AuthURLParams{APIKey: a.APIKey, Callback: callbackURL})
+	p := AuthURLParams{APIKey: a.APIKey, Callback: callbackURL}
+	return buildAuthURL(a.AuthEndpointURL(), p)
 }
 
 // AuthTokenURL returns the authorization URL for the Last.fm API with a
@@ -158,7 +278,31 @@ func (a *API) AuthCallbackURL(callbackURL string) string {
 //
 // https://www.last.fm/api/desktopauth
 func (a *API) AuthTokenURL(token string) string {
-	return AuthURL(AuthURLParams{APIKey: a.APIKey, Token: token})
+	p := AuthURLParams{APIKey: a.APIKey, Token: token}
+	return buildAuthURL(a.AuthEndpointURL(), p)
+}
+
+// EndpointURL returns the URL of the API endpoint requests are sent to.
+func (a *API) EndpointURL() string {
+	if a.Endpoint == "" {
+		return Endpoint
+	}
+	return a.Endpoint
+}
+
+// AuthEndpointURL returns the URL users are sent to in order to authorize the
+// application.
+func (a *API) AuthEndpointURL() string {
+	if a.AuthEndpoint == "" {
+		return lastfm.AuthURL
+	}
+	return a.AuthEndpoint
+}
+
+// BuildAPIURL constructs an API URL for the API client's endpoint with the
+// specified parameters.
+func (a *API) BuildAPIURL(params url.Values) string {
+	return a.EndpointURL() + "?" + params.Encode()
 }
 
 // Signature generates a signature for the given parameters using the API
@@ -212,7 +356,13 @@ func (a *API) CheckCredentials(level RequestLevel) error {
 // Returns:
 //   - An error if the request fails or the response cannot be decoded.
 func (a *API) Get(dest any, method APIMethod, params any) error {
-	return a.Request(dest, http.MethodGet, method, params)
+	return a.GetContext(context.Background(), dest, method, params)
+}
+
+// GetContext is like Get but uses the given context for the request. If the
+// context is cancelled, the request and any pending retries are aborted.
+func (a *API) GetContext(ctx context.Context, dest any, method APIMethod, params any) error {
+	return a.RequestContext(ctx, dest, http.MethodGet, method, params)
 }
 
 // Post sends an HTTP POST request to the API using the specified method and
@@ -226,7 +376,13 @@ func (a *API) Get(dest any, method APIMethod, params any) error {
 // Returns:
 //   - An error if the request fails or the response cannot be decoded.
 func (a *API) Post(dest any, method APIMethod, params any) error {
-	return a.Request(dest, http.MethodPost, method, params)
+	return a.PostContext(context.Background(), dest, method, params)
+}
+
+// PostContext is like Post but uses the given context for the request. If the
+// context is cancelled, the request and any pending retries are aborted.
+func (a *API) PostContext(ctx context.Context, dest any, method APIMethod, params any) error {
+	return a.RequestContext(ctx, dest, http.MethodPost, method, params)
 }
 
 // Request sends an HTTP request to the API with the specified parameters and
@@ -244,6 +400,15 @@ func (a *API) Post(dest any, method APIMethod, params any) error {
 //   - An error if the request fails, the response cannot be unmarshaled,
 //     or any other issue occurs.
 func (a *API) Request(dest any, httpMethod string, method APIMethod, params any) error {
+	return a.RequestContext(context.Background(), dest, httpMethod, method, params)
+}
+
+// RequestContext is like Request but uses the given context for the request.
+// If the context is cancelled, the request and any pending retries are
+// aborted.
+func (a *API) RequestContext(
+	ctx context.Context, dest any, httpMethod string, method APIMethod, params any) error {
+
 	err := a.CheckCredentials(RequestLevelAPIKey)
 	if err != nil {
 		return err
@@ -259,9 +424,9 @@ func (a *API) Request(dest any, httpMethod string, method APIMethod, params any)
 
 	switch httpMethod {
 	case http.MethodGet:
-		return a.GetURL(dest, BuildAPIURL(p))
+		return a.GetURLContext(ctx, dest, a.BuildAPIURL(p))
 	case http.MethodPost:
-		return a.PostBody(dest, Endpoint, p.Encode())
+		return a.PostBodyContext(ctx, dest, a.EndpointURL(), p.Encode())
 	default:
 		return errors.New("unsupported HTTP method")
 	}
@@ -271,14 +436,26 @@ func (a *API) Request(dest any, httpMethod string, method APIMethod, params any)
 // parameters, signed with the API secret. The response is unmarshaled into the
 // provided destination.
 func (a *API) GetSigned(dest any, method APIMethod, params any) error {
-	return a.RequestSigned(dest, http.MethodGet, method, params)
+	return a.GetSignedContext(context.Background(), dest, method, params)
+}
+
+// GetSignedContext is like GetSigned but uses the given context for the
+// request.
+func (a *API) GetSignedContext(ctx context.Context, dest any, method APIMethod, params any) error {
+	return a.RequestSignedContext(ctx, dest, http.MethodGet, method, params)
 }
 
 // PostSigned sends an HTTP POST request to the API with the specified method
 // and parameters, signed with the API secret. The response is unmarshaled into
 // the provided destination.
 func (a *API) PostSigned(dest any, method APIMethod, params any) error {
-	return a.RequestSigned(dest, http.MethodPost, method, params)
+	return a.PostSignedContext(context.Background(), dest, method, params)
+}
+
+// PostSignedContext is like PostSigned but uses the given context for the
+// request.
+func (a *API) PostSignedContext(ctx context.Context, dest any, method APIMethod, params any) error {
+	return a.RequestSignedContext(ctx, dest, http.MethodPost, method, params)
 }
 
 // RequestSigned sends an HTTP request to the API with the specified method and
@@ -297,6 +474,15 @@ func (a *API) PostSigned(dest any, method APIMethod, params any) error {
 //   - An error if the request fails, the response cannot be unmarshaled, or any
 //     other issue occurs.
 func (a *API) RequestSigned(dest any, httpMethod string, method APIMethod, params any) error {
+	return a.RequestSignedContext(context.Background(), dest, httpMethod, method, params)
+}
+
+// RequestSignedContext is like RequestSigned but uses the given context for
+// the request. If the context is cancelled, the request and any pending
+// retries are aborted.
+func (a *API) RequestSignedContext(
+	ctx context.Context, dest any, httpMethod string, method APIMethod, params any) error {
+
 	err := a.CheckCredentials(RequestLevelSecret)
 	if err != nil {
 		return err
@@ -313,9 +499,9 @@ func (a *API) RequestSigned(dest any, httpMethod string, method APIMethod, param
 
 	switch httpMethod {
 	case http.MethodGet:
-		return a.GetURL(dest, BuildAPIURL(p))
+		return a.GetURLContext(ctx, dest, a.BuildAPIURL(p))
 	case http.MethodPost:
-		return a.PostBody(dest, Endpoint, p.Encode())
+		return a.PostBodyContext(ctx, dest, a.EndpointURL(), p.Encode())
 	default:
 		return errors.New("unsupported HTTP method")
 	}
@@ -324,88 +510,297 @@ func (a *API) RequestSigned(dest any, httpMethod string, method APIMethod, param
 // GetURL sends an HTTP GET request to the specified URL and unmarshals the
 // response into the provided destination.
 func (a *API) GetURL(dest any, url string) error {
-	return a.tryRequest(dest, http.MethodGet, url, "")
+	return a.GetURLContext(context.Background(), dest, url)
+}
+
+// GetURLContext is like GetURL but uses the given context for the request.
+func (a *API) GetURLContext(ctx context.Context, dest any, url string) error {
+	return a.tryRequest(ctx, dest, http.MethodGet, url, "")
 }
 
 // PostBody sends an HTTP POST request to the specified URL with the given
 // request body and unmarshals the response into the provided destination.
 func (a *API) PostBody(dest any, url, body string) error {
-	return a.tryRequest(dest, http.MethodPost, url, body)
+	return a.PostBodyContext(context.Background(), dest, url, body)
+}
+
+// PostBodyContext is like PostBody but uses the given context for the
+// request.
+func (a *API) PostBodyContext(ctx context.Context, dest any, url, body string) error {
+	return a.tryRequest(ctx, dest, http.MethodPost, url, body)
+}
+
+func (a *API) tryRequest(ctx context.Context, dest any, method, url, body string) error {
+	call, err := newCall(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	ctx, done := a.observe(ctx, call)
+	lfm, err := a.invoker()(ctx, call)
+	done(err)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalInner(dest, lfm)
+}
+
+// invoke performs a call to the API, from the cache if possible, and returns
+// the response. It's the last invoker of the interceptor chain.
+func (a *API) invoke(ctx context.Context, call *Call) (LFMWrapper, error) {
+	var (
+		lfm LFMWrapper
+		err error
+	)
+
+	method := call.HTTPMethod
+	url, body := call.request()
+
+	url, body, err = setFormat(a.Format, method, url, body)
+	if err != nil {
+		return lfm, err
+	}
+
+	var (
+		cacheKey string
+		cacheTTL time.Duration
+	)
+	if a.Cache != nil {
+		cacheKey, cacheTTL = a.Cache.key(method, url)
+	}
+	if cacheTTL > 0 {
+		if data, ok := a.Cache.Store.Get(cacheKey); ok {
+			if err = decodeResponse(a.Format, bytes.NewReader(data), &lfm); err == nil {
+				if info := responseInfo(ctx); info != nil {
+					*info = ResponseInfo{URL: RedactURL(url), Body: data, Cached: true}
+				}
+				return lfm, nil
+			}
+			lfm = LFMWrapper{}
+		}
+	}
+
+	info := responseInfo(ctx)
+	coalesce := a.Coalesce && isUnsignedGet(method, url)
+	// The result of a coalesced request is shared by callers that may want
+	// the response, so it's always captured.
+	capture := a.CaptureResponses || info != nil || coalesce
+
+	send := func(ctx context.Context) (sendResult, error) {
+		sr, err := a.send(ctx, method, url, body)
+		annotateError(err, call.Method, sr.attempts)
+		if capture {
+			attachResponse(err, sr.info)
+		}
+		return sr, err
+	}
+
+	var sr sendResult
+	var shared bool
+	if coalesce {
+		sr, shared, err = a.inflight.do(ctx, url, send)
+	} else {
+		sr, err = send(ctx)
+	}
+	call.StatusCode, call.Attempts, call.Coalesced = sr.statusCode, sr.attempts, shared
+	if shared {
+		// Only the caller that sent the request counts its attempts.
+		call.Attempts = 0
+	}
+	if info != nil {
+		*info = ResponseInfo{}
+		if sr.info != nil {
+			*info = *sr.info
+		}
+	}
+	if err != nil {
+		return sr.lfm, err
+	}
+
+	if cacheTTL > 0 {
+		a.Cache.Store.Set(cacheKey, sr.raw, cacheTTL)
+	}
+	if a.Cache != nil && method == http.MethodPost {
+		a.Cache.invalidateBody(body)
+	}
+
+	return sr.lfm, nil
+}
+
+// sendResult is the result of sending a request to the API.
+type sendResult struct {
+	// lfm is the decoded response, and raw its body, if the request succeeded.
+	lfm LFMWrapper
+	raw []byte
+	// statusCode is the HTTP status code of the last attempt, or 0 if no
+	// response was received.
+	statusCode int
+	// attempts is the number of attempts made.
+	attempts uint
+	// info is the raw response to the last attempt, or nil if no response was
+	// received.
+	info *ResponseInfo
 }
 
-func (a *API) tryRequest(dest any, method, url, body string) error {
+// send sends a request to the API, retrying failed attempts as allowed by the
+// retry policy, and returns the decoded response and its raw body.
+func (a *API) send(ctx context.Context, method, url, body string) (sendResult, error) {
 	var (
-		res   *http.Response
-		lfm   LFMWrapper
-		lferr *LastFMError
-		err   error
+		sr     sendResult
+		res    *http.Response
+		lfm    LFMWrapper
+		lferr  *LastFMError
+		raw    []byte
+		waited time.Duration
+		err    error
 	)
 
-	for i := uint(0); i <= a.Retries; i++ {
+	for attempt := uint(1); ; attempt++ {
+		if err = ctx.Err(); err != nil {
+			return sr, err
+		}
+		if a.Breaker != nil {
+			if err = a.Breaker.Allow(); err != nil {
+				return sr, err
+			}
+		}
+		if a.Limiter != nil {
+			if err = a.Limiter.Wait(ctx); err != nil {
+				a.Breaker.release()
+				return sr, err
+			}
+		}
+
 		var req *http.Request
 
 		switch method {
 		case http.MethodGet:
-			req, err = a.createGetRequest(url)
+			req, err = a.createGetRequest(ctx, url)
 		case http.MethodPost:
-			req, err = a.createPostRequest(url, body)
+			req, err = a.createPostRequest(ctx, url, body)
 		default:
-			req, err = a.createRequest(method, url, body)
+			req, err = a.createRequest(ctx, method, url, body)
 		}
 		if err != nil {
-			return err
+			a.Breaker.release()
+			return sr, err
 		}
 
+		lfm, lferr, raw = LFMWrapper{}, nil, nil
+		start := time.Now()
+
+		sr.attempts = attempt
+		sr.statusCode = 0
+
 		res, err = a.Client.Do(req)
-		if err != nil {
-			return err
+		if err == nil {
+			sr.statusCode = res.StatusCode
+			raw, err = io.ReadAll(res.Body)
+			res.Body.Close()
+			if err == nil {
+				err = decodeResponse(a.Format, bytes.NewReader(raw), &lfm)
+			}
+			if err == nil {
+				lferr, _ = lfm.UnwrapError()
+			}
 		}
 
-		err = xml.NewDecoder(res.Body).Decode(&lfm)
-		res.Body.Close()
-		if err == nil {
-			lferr, _ = lfm.UnwrapError()
+		attemptErr := err
+		switch {
+		case res == nil:
+		case lferr != nil:
+			attemptErr = lferr
+		case res.StatusCode < http.StatusOK || res.StatusCode >= 300:
+			attemptErr = NewHTTPError(res)
 		}
 
-		if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
-			continue
+		at := RetryAttempt{
+			Attempt:  attempt,
+			Response: res,
+			Err:      attemptErr,
+			Waited:   waited,
+			Latency:  time.Since(start),
 		}
-		if lferr != nil && lferr.ShouldRetry() {
-			continue
+		a.logAttempt(ctx, req, body, at, raw)
+		sr.info = newResponseInfo(req, res, raw, attempt)
+		if a.Breaker != nil {
+			a.Breaker.Record(attemptErr)
 		}
 
-		break
+		if attempt > a.Retries || attemptErr == nil {
+			break
+		}
+
+		delay, retry := a.retryPolicy().Retry(at)
+		if !retry {
+			break
+		}
+		// Stop retrying once the breaker opens, returning the last error.
+		if a.Breaker != nil && a.Breaker.State() == BreakerOpen {
+			break
+		}
+		if a.OnRetry != nil {
+			a.OnRetry(at, delay)
+		}
+
+		if err = sleep(ctx, delay); err != nil {
+			return sr, err
+		}
+		waited += delay
 	}
 
+	if res == nil {
+		return sr, err
+	}
 	if lferr != nil {
-		return lferr.WrapResponse(res)
+		return sr, lferr.WrapResponse(res)
 	}
 	if res.StatusCode < http.StatusOK || res.StatusCode >= 300 {
-		return NewHTTPError(res)
+		return sr, NewHTTPError(res)
 	}
 	if errors.Is(err, io.EOF) {
-		return fmt.Errorf("invalid XML response: %w", err)
+		return sr, fmt.Errorf("invalid %s response: %w", a.format(), err)
 	}
 	if err != nil {
-		return err
+		return sr, err
 	}
 
+	sr.lfm, sr.raw = lfm, raw
+	return sr, nil
+}
+
+func unmarshalInner(dest any, lfm LFMWrapper) error {
 	if dest == nil {
 		return nil
 	}
-	if err = lfm.UnmarshalInnerXML(dest); err != nil {
+	if err := lfm.UnmarshalInnerXML(dest); err != nil {
 		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	return nil
 }
 
-func (a *API) createGetRequest(url string) (*http.Request, error) {
-	return a.createRequest(http.MethodGet, url, "")
+func (a *API) format() Format {
+	if a.Format == "" {
+		return FormatXML
+	}
+	return a.Format
 }
 
-func (a *API) createPostRequest(url, body string) (*http.Request, error) {
-	req, err := a.createRequest(http.MethodPost, url, body)
+func (a *API) retryPolicy() RetryPolicy {
+	if a.RetryPolicy == nil {
+		return NoBackoff
+	}
+	return a.RetryPolicy
+}
+
+func (a *API) createGetRequest(ctx context.Context, url string) (*http.Request, error) {
+	return a.createRequest(ctx, http.MethodGet, url, "")
+}
+
+func (a *API) createPostRequest(ctx context.Context, url, body string) (*http.Request, error) {
+	req, err := a.createRequest(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -415,19 +810,21 @@ func (a *API) createPostRequest(url, body string) (*http.Request, error) {
 	return req, nil
 }
 
-func (a *API) createRequest(method, url, body string) (*http.Request, error) {
+func (a *API) createRequest(
+	ctx context.Context, method, url, body string) (*http.Request, error) {
+
 	var r io.Reader
 	if body != "" {
 		r = strings.NewReader(body)
 	}
 
-	req, err := http.NewRequest(method, url, r)
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", a.UserAgent)
-	req.Header.Set("Accept", "application/xml")
+	req.Header.Set("Accept", "application/"+string(a.format()))
 
 	return req, nil
 }
@@ -450,6 +847,10 @@ func BuildAPIURL(params url.Values) string {
 // Returns:
 //   - The authentication URL as a string.
 func AuthURL(params AuthURLParams) string {
+	return buildAuthURL(lastfm.AuthURL, params)
+}
+
+func buildAuthURL(authURL string, params AuthURLParams) string {
 	p := url.Values{}
 
 	p.Set("api_key", params.APIKey)
@@ -461,7 +862,7 @@ func AuthURL(params AuthURLParams) string {
 		p.Set("token", params.Token)
 	}
 
-	return lastfm.AuthURL + "?" + p.Encode()
+	return authURL + "?" + p.Encode()
 }
 
 // Signature generates a Last.fm API signature for the given parameters and
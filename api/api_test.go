@@ -1,13 +1,24 @@
 package api
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/twoscott/gobble-fm/lastfm"
 )
 
 var (
@@ -16,14 +27,17 @@ var (
 )
 
 type mockHTTPClient struct {
+	mu          sync.Mutex
 	tries       uint
 	capturedReq *http.Request
 	doFunc      func(req *http.Request) (*http.Response, error)
 }
 
 func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
 	m.capturedReq = req
 	m.tries++
+	m.mu.Unlock()
 	return m.doFunc(req)
 }
 
@@ -637,6 +651,1832 @@ func TestAPI_RequestSigned(t *testing.T) {
 	}
 }
 
+func TestAPI_GetContext(t *testing.T) {
+	cases := []struct {
+		name string
+
+		cancelBefore bool
+		cancelOnTry  uint
+
+		mockStatusCode int
+
+		wantError error
+		wantTries uint
+	}{
+		{
+			name:           "Context cancelled before request",
+			cancelBefore:   true,
+			mockStatusCode: http.StatusOK,
+			wantError:      context.Canceled,
+			wantTries:      0,
+		},
+		{
+			name:           "Context cancelled aborts retries",
+			cancelOnTry:    1,
+			mockStatusCode: http.StatusInternalServerError,
+			wantError:      context.Canceled,
+			wantTries:      1,
+		},
+		{
+			name:           "Context cancelled on later retry",
+			cancelOnTry:    3,
+			mockStatusCode: http.StatusServiceUnavailable,
+			wantError:      context.Canceled,
+			wantTries:      3,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mockClient := &mockHTTPClient{}
+			mockClient.doFunc = func(req *http.Request) (*http.Response, error) {
+				if req.Context() != ctx {
+					t.Errorf("expected request to carry the caller's context")
+				}
+				if mockClient.tries == c.cancelOnTry {
+					cancel()
+				}
+				return &http.Response{
+					StatusCode: c.mockStatusCode,
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Retries:   DefaultRetries,
+				Client:    mockClient,
+			}
+
+			if c.cancelBefore {
+				cancel()
+			}
+
+			err := api.GetContext(ctx, nil, UserGetInfoMethod, nil)
+			if !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+
+			if c.wantTries != mockClient.tries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+		})
+	}
+}
+
+func TestAPI_RetryPolicy(t *testing.T) {
+	addrErr := &net.AddrError{Err: "missing port in address", Addr: "localhost"}
+
+	cases := []struct {
+		name string
+
+		retryAfter string
+		baseDelay  time.Duration
+		mockStatus int
+		mockError  error
+
+		wantError   error
+		wantTries   uint
+		wantRetries int
+		wantDelay   time.Duration
+	}{
+		{
+			name:        "Transient network error is retried",
+			mockError:   &url.Error{Op: "Get", Err: syscall.ECONNRESET},
+			wantError:   syscall.ECONNRESET,
+			wantTries:   maxTries,
+			wantRetries: int(DefaultRetries),
+		},
+		{
+			name:        "Non-transient network error is not retried",
+			mockError:   errNetwork,
+			wantError:   errNetwork,
+			wantTries:   1,
+			wantRetries: 0,
+		},
+		{
+			name:        "Non-temporary dial error is not retried",
+			mockError:   &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Net: "tcp", Err: addrErr}},
+			wantError:   addrErr,
+			wantTries:   1,
+			wantRetries: 0,
+		},
+		{
+			name: "Transient dial error is retried",
+			mockError: &url.Error{Op: "Get", Err: &net.OpError{
+				Op:  "dial",
+				Net: "tcp",
+				Err: syscall.ECONNREFUSED,
+			}},
+			wantError:   syscall.ECONNREFUSED,
+			wantTries:   maxTries,
+			wantRetries: int(DefaultRetries),
+		},
+		{
+			name:        "Retry-After header is honored",
+			retryAfter:  "1",
+			baseDelay:   10 * time.Millisecond,
+			mockStatus:  http.StatusTooManyRequests,
+			wantError:   &HTTPError{StatusCode: http.StatusTooManyRequests},
+			wantTries:   2,
+			wantRetries: 1,
+			wantDelay:   time.Second,
+		},
+		{
+			name:        "Total wait is capped",
+			retryAfter:  "60",
+			mockStatus:  http.StatusServiceUnavailable,
+			wantError:   &HTTPError{StatusCode: http.StatusServiceUnavailable},
+			wantTries:   1,
+			wantRetries: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					if c.mockError != nil {
+						return nil, c.mockError
+					}
+					return &http.Response{
+						StatusCode: c.mockStatus,
+						Header:     http.Header{"Retry-After": {c.retryAfter}},
+						Body:       io.NopCloser(strings.NewReader("")),
+					}, nil
+				},
+			}
+
+			var retries int
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Retries:   DefaultRetries,
+				RetryPolicy: &ExponentialBackoff{
+					BaseDelay: c.baseDelay,
+					MaxDelay:  c.baseDelay,
+					MaxWait:   time.Second,
+				},
+				OnRetry: func(at RetryAttempt, delay time.Duration) {
+					retries++
+					if at.Attempt != uint(retries) {
+						t.Errorf("expected attempt %d, got %d", retries, at.Attempt)
+					}
+					if delay != c.wantDelay {
+						t.Errorf("expected delay %v, got %v", c.wantDelay, delay)
+					}
+				},
+				Client: mockClient,
+			}
+
+			err := api.Get(nil, UserGetInfoMethod, nil)
+			if !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+
+			if c.wantTries != mockClient.tries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+			if c.wantRetries != retries {
+				t.Errorf("expected %d retry hook calls, got %d", c.wantRetries, retries)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff_Backoff(t *testing.T) {
+	b := ExponentialBackoff{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  time.Second,
+	}
+
+	cases := []struct {
+		attempt uint
+		ceil    time.Duration
+	}{
+		{attempt: 1, ceil: 100 * time.Millisecond},
+		{attempt: 2, ceil: 200 * time.Millisecond},
+		{attempt: 4, ceil: 800 * time.Millisecond},
+		{attempt: 5, ceil: time.Second},
+		{attempt: 50, ceil: time.Second},
+	}
+
+	for _, c := range cases {
+		for range 100 {
+			if d := b.Backoff(c.attempt); d < 0 || d > c.ceil {
+				t.Fatalf("attempt %d: expected delay in [0, %v], got %v", c.attempt, c.ceil, d)
+			}
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	cases := []struct {
+		name string
+
+		rate     float64
+		burst    int
+		failFast bool
+		requests int
+		timeout  time.Duration
+
+		wantAllowed int
+		wantError   error
+	}{
+		{
+			name:        "Burst allowed immediately",
+			rate:        1,
+			burst:       3,
+			requests:    3,
+			wantAllowed: 3,
+		},
+		{
+			name:        "Fail fast when bucket is empty",
+			rate:        1,
+			burst:       2,
+			failFast:    true,
+			requests:    3,
+			wantAllowed: 2,
+			wantError:   ErrRateLimited,
+		},
+		{
+			name:        "Blocking wait respects context",
+			rate:        0.1,
+			burst:       1,
+			requests:    2,
+			timeout:     10 * time.Millisecond,
+			wantAllowed: 1,
+			wantError:   context.DeadlineExceeded,
+		},
+		{
+			name:        "Blocking wait refills",
+			rate:        1000,
+			burst:       1,
+			requests:    5,
+			timeout:     time.Second,
+			wantAllowed: 5,
+		},
+		{
+			name:        "Zero rate is unlimited",
+			rate:        0,
+			burst:       1,
+			requests:    5,
+			wantAllowed: 5,
+		},
+		{
+			name:        "Zero rate is unlimited when failing fast",
+			rate:        0,
+			burst:       1,
+			failFast:    true,
+			requests:    5,
+			wantAllowed: 5,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			l := NewRateLimiter(c.rate, c.burst)
+			l.SetFailFast(c.failFast)
+
+			ctx := context.Background()
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+
+			var allowed int
+			var err error
+			for range c.requests {
+				if err = l.Wait(ctx); err != nil {
+					break
+				}
+				allowed++
+			}
+
+			if allowed != c.wantAllowed {
+				t.Errorf("expected %d allowed requests, got %d", c.wantAllowed, allowed)
+			}
+			if !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+		})
+	}
+}
+
+func TestAPI_Limiter(t *testing.T) {
+	l := NewRateLimiter(1, 2)
+	l.SetFailFast(true)
+
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(strings.NewReader("")),
+			}, nil
+		},
+	}
+
+	api := &API{
+		APIKey:    "testapikey",
+		UserAgent: DefaultUserAgent,
+		Retries:   DefaultRetries,
+		Limiter:   l,
+		Client:    mockClient,
+	}
+
+	err := api.Get(nil, UserGetInfoMethod, nil)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected error %v, got %v", ErrRateLimited, err)
+	}
+	if mockClient.tries != 2 {
+		t.Errorf("expected 2 tries, got %d", mockClient.tries)
+	}
+}
+
+func TestAPI_JSONFormat(t *testing.T) {
+	cases := []struct {
+		name string
+
+		response string
+
+		wantTracks []lastfm.Track
+		wantError  error
+	}{
+		{
+			name: "Tracks",
+			response: `{"recenttracks":{"track":[` +
+				`{"artist":{"mbid":"","#text":"Artist"},"streamable":"0",` +
+				`"image":[{"size":"small","#text":"https://example.com/s.png"}],` +
+				`"mbid":"","album":{"mbid":"","#text":"Album"},"name":"Playing",` +
+				`"@attr":{"nowplaying":"true"},"url":"https://example.com/1"},` +
+				`{"artist":{"mbid":"","#text":"Artist"},"streamable":"1","image":[],` +
+				`"mbid":"","album":{"mbid":"","#text":""},"name":"Played",` +
+				`"url":"https://example.com/2",` +
+				`"date":{"uts":"1700000000","#text":"14 Nov 2023, 22:13"}}],` +
+				`"@attr":{"user":"testuser","totalPages":"1","page":"1","perPage":"2","total":"2"}}}`,
+			wantTracks: []lastfm.Track{
+				{Title: "Playing", NowPlaying: true},
+				{
+					Title:       "Played",
+					Streamable:  true,
+					ScrobbledAt: lastfm.DateTime(time.Unix(1700000000, 0)),
+				},
+			},
+		},
+		{
+			name: "Single track",
+			response: `{"recenttracks":{"track":{"name":"Played","artist":{"#text":"Artist"}},` +
+				`"@attr":{"user":"testuser","totalPages":"1","page":"1"}}}`,
+			wantTracks: []lastfm.Track{{Title: "Played"}},
+		},
+		{
+			name:      "Error",
+			response:  `{"error":6,"message":"User not found"}`,
+			wantError: &LastFMError{Code: ErrInvalidParameters},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					if f := req.URL.Query().Get("format"); f != "json" {
+						t.Errorf("expected format json, got %q", f)
+					}
+					if a := req.Header.Get("Accept"); a != "application/json" {
+						t.Errorf("expected Accept application/json, got %q", a)
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(c.response)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Format:    FormatJSON,
+				Client:    mockClient,
+			}
+
+			var res lastfm.RecentTracks
+			params := lastfm.RecentTracksParams{User: "testuser"}
+			err := api.Get(&res, UserGetRecentTracksMethod, params)
+
+			if c.wantError != nil {
+				if !errors.Is(err, c.wantError) {
+					t.Errorf("expected error %v, got %v", c.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.User != "testuser" || res.Page != 1 || res.TotalPages != 1 {
+				t.Errorf("unexpected attributes: %+v", res)
+			}
+			if len(res.Tracks) != len(c.wantTracks) {
+				t.Fatalf("expected %d tracks, got %d", len(c.wantTracks), len(res.Tracks))
+			}
+			for i, want := range c.wantTracks {
+				got := res.Tracks[i]
+				if got.Title != want.Title ||
+					got.NowPlaying != want.NowPlaying ||
+					got.Streamable != want.Streamable ||
+					got.ScrobbledAt.Unix() != want.ScrobbledAt.Unix() {
+					t.Errorf("track %d: expected %+v, got %+v", i, want, got)
+				}
+				if got.Artist.Name != "Artist" {
+					t.Errorf("track %d: expected artist Artist, got %q", i, got.Artist.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestAPI_Cache(t *testing.T) {
+	type request struct {
+		httpMethod string
+		signed     bool
+		method     APIMethod
+		params     any
+	}
+
+	type trackParams struct {
+		Artist string `url:"artist"`
+		Track  string `url:"track"`
+	}
+
+	type scrobbleParams struct {
+		Artist0 string `url:"artist[0]"`
+		Track0  string `url:"track[0]"`
+		Artist1 string `url:"artist[1]"`
+		Track1  string `url:"track[1]"`
+	}
+
+	info := request{http.MethodGet, false, TrackGetInfoMethod, trackParams{"Artist", "Track"}}
+
+	cases := []struct {
+		name string
+
+		requests []request
+
+		wantTries uint
+	}{
+		{
+			name:      "Cached method",
+			requests:  []request{info, info},
+			wantTries: 1,
+		},
+		{
+			name: "Different params",
+			requests: []request{
+				info,
+				{http.MethodGet, false, TrackGetInfoMethod, trackParams{"Artist", "Other"}},
+			},
+			wantTries: 2,
+		},
+		{
+			name: "Uncached method",
+			requests: []request{
+				{http.MethodGet, false, UserGetRecentTracksMethod, nil},
+				{http.MethodGet, false, UserGetRecentTracksMethod, nil},
+			},
+			wantTries: 2,
+		},
+		{
+			name: "Signed request",
+			requests: []request{
+				{http.MethodGet, true, TrackGetInfoMethod, trackParams{"Artist", "Track"}},
+				{http.MethodGet, true, TrackGetInfoMethod, trackParams{"Artist", "Track"}},
+			},
+			wantTries: 2,
+		},
+		{
+			name: "Invalidated by write",
+			requests: []request{
+				info,
+				{http.MethodPost, true, TrackLoveMethod, trackParams{"artist", "track"}},
+				info,
+			},
+			wantTries: 3,
+		},
+		{
+			name: "Not invalidated by unrelated write",
+			requests: []request{
+				info,
+				{http.MethodPost, true, TrackLoveMethod, trackParams{"Artist", "Other"}},
+				{http.MethodPost, true, ArtistAddTagsMethod, trackParams{"Artist", ""}},
+				info,
+			},
+			wantTries: 3,
+		},
+		{
+			name: "Invalidated by batched write",
+			requests: []request{
+				info,
+				{http.MethodPost, true, TrackScrobbleMethod,
+					scrobbleParams{"Other", "Other", "artist", "track"}},
+				info,
+			},
+			wantTries: 3,
+		},
+		{
+			name: "Not invalidated by unrelated batched write",
+			requests: []request{
+				info,
+				{http.MethodPost, true, TrackScrobbleMethod,
+					scrobbleParams{"Artist", "Other", "Other", "Track"}},
+				info,
+			},
+			wantTries: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(
+							`<lfm status="ok"><track><name>Track</name></track></lfm>`)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				Secret:    "testsecret",
+				UserAgent: DefaultUserAgent,
+				Cache:     NewMemoryCache(),
+				Client:    mockClient,
+			}
+
+			for _, r := range c.requests {
+				var err error
+				if r.signed {
+					err = api.RequestSigned(nil, r.httpMethod, r.method, r.params)
+				} else {
+					err = api.Request(nil, r.httpMethod, r.method, r.params)
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if mockClient.tries != c.wantTries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+		})
+	}
+}
+
+// metricsFunc is a Metrics that calls itself with the stats of each call.
+type metricsFunc func(stats CallStats)
+
+func (f metricsFunc) RecordCall(stats CallStats) { f(stats) }
+
+func TestAPI_Coalesce(t *testing.T) {
+	const callers = 10
+
+	cases := []struct {
+		name string
+
+		signed         bool
+		retries        uint
+		mockStatusCode int
+		mockBody       string
+
+		wantTries     uint
+		wantCoalesced int
+		wantError     error
+	}{
+		{
+			name:           "Unsigned request",
+			mockStatusCode: http.StatusOK,
+			mockBody:       `<lfm status="ok"><artist><name>Artist</name></artist></lfm>`,
+			wantTries:      1,
+			wantCoalesced:  callers - 1,
+		},
+		{
+			name:           "Error",
+			mockStatusCode: http.StatusOK,
+			mockBody:       `<lfm status="failed"><error code="6">Artist not found</error></lfm>`,
+			wantTries:      1,
+			wantCoalesced:  callers - 1,
+			wantError:      &LastFMError{Code: ErrInvalidParameters},
+		},
+		{
+			name:           "Retried error",
+			mockStatusCode: http.StatusOK,
+			mockBody:       `<lfm status="failed"><error code="16">Service temporarily unavailable</error></lfm>`,
+			retries:        2,
+			wantTries:      3,
+			wantCoalesced:  callers - 1,
+			wantError:      ErrServiceUnavailableError,
+		},
+		{
+			name:           "Signed request",
+			signed:         true,
+			mockStatusCode: http.StatusOK,
+			mockBody:       `<lfm status="ok"><artist><name>Artist</name></artist></lfm>`,
+			wantTries:      callers,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			release := make(chan struct{})
+
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					<-release
+					return &http.Response{
+						StatusCode: c.mockStatusCode,
+						Body:       io.NopCloser(strings.NewReader(c.mockBody)),
+					}, nil
+				},
+			}
+			api := &API{
+				APIKey:    "testapikey",
+				Secret:    "testsecret",
+				UserAgent: DefaultUserAgent,
+				Retries:   c.retries,
+				Coalesce:  true,
+				Client:    mockClient,
+			}
+
+			var mu sync.Mutex
+			var stats []CallStats
+			api.SetMetrics(metricsFunc(func(s CallStats) {
+				mu.Lock()
+				defer mu.Unlock()
+				stats = append(stats, s)
+			}))
+
+			params := lastfm.ArtistInfoParams{Artist: "Artist"}
+			results := make([]lastfm.ArtistInfo, callers)
+			errs := make([]error, callers)
+
+			var wg sync.WaitGroup
+			for i := range callers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if c.signed {
+						errs[i] = api.GetSigned(&results[i], ArtistGetInfoMethod, params)
+					} else {
+						errs[i] = api.Get(&results[i], ArtistGetInfoMethod, params)
+					}
+				}()
+			}
+
+			if !c.signed {
+				for waiters := 0; waiters < callers; {
+					time.Sleep(time.Millisecond)
+					api.inflight.mu.Lock()
+					waiters = 0
+					for _, f := range api.inflight.calls {
+						waiters += f.waiters
+					}
+					api.inflight.mu.Unlock()
+				}
+			}
+			close(release)
+			wg.Wait()
+
+			if mockClient.tries != c.wantTries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+
+			var attempts uint
+			var coalesced int
+			for _, s := range stats {
+				attempts += s.Attempts
+				if s.Coalesced {
+					coalesced++
+					if s.Attempts != 0 {
+						t.Errorf("expected 0 attempts for coalesced call, got %d", s.Attempts)
+					}
+				}
+			}
+			if attempts != c.wantTries {
+				t.Errorf("expected %d recorded attempts, got %d", c.wantTries, attempts)
+			}
+			if coalesced != c.wantCoalesced {
+				t.Errorf("expected %d coalesced calls, got %d", c.wantCoalesced, coalesced)
+			}
+
+			for i := range callers {
+				if c.wantError != nil {
+					if !errors.Is(errs[i], c.wantError) {
+						t.Errorf("caller %d: expected error %v, got %v", i, c.wantError, errs[i])
+					}
+					continue
+				}
+				if errs[i] != nil {
+					t.Errorf("caller %d: unexpected error: %v", i, errs[i])
+				}
+				if results[i].Name != "Artist" {
+					t.Errorf("caller %d: expected name Artist, got %q", i, results[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestAPI_Use(t *testing.T) {
+	errInjected := errors.New("injected error")
+
+	cases := []struct {
+		name string
+
+		interceptors func(calls *[]string) []Interceptor
+
+		wantCalls []string
+		wantName  string
+		wantURL   string
+		wantTries uint
+		wantError error
+	}{
+		{
+			name: "Order",
+			interceptors: func(calls *[]string) []Interceptor {
+				record := func(name string) Interceptor {
+					return func(ctx context.Context, call *Call, next Invoker) (LFMWrapper, error) {
+						*calls = append(*calls, name+" "+call.Method.String())
+						lfm, err := next(ctx, call)
+						*calls = append(*calls, name+" done")
+						return lfm, err
+					}
+				}
+				return []Interceptor{record("first"), record("second")}
+			},
+			wantCalls: []string{
+				"first user.getInfo", "second user.getInfo", "second done", "first done",
+			},
+			wantName:  "testuser",
+			wantURL:   Endpoint + "?api_key=testapikey&method=user.getInfo&user=testuser",
+			wantTries: 1,
+		},
+		{
+			name: "Modified params",
+			interceptors: func(calls *[]string) []Interceptor {
+				return []Interceptor{
+					func(ctx context.Context, call *Call, next Invoker) (LFMWrapper, error) {
+						call.Params.Set("lang", "en")
+						return next(ctx, call)
+					},
+				}
+			},
+			wantName:  "testuser",
+			wantURL:   Endpoint + "?api_key=testapikey&lang=en&method=user.getInfo&user=testuser",
+			wantTries: 1,
+		},
+		{
+			name: "Injected error",
+			interceptors: func(calls *[]string) []Interceptor {
+				return []Interceptor{
+					func(ctx context.Context, call *Call, next Invoker) (LFMWrapper, error) {
+						return LFMWrapper{}, errInjected
+					},
+				}
+			},
+			wantTries: 0,
+			wantError: errInjected,
+		},
+		{
+			name: "Replaced response",
+			interceptors: func(calls *[]string) []Interceptor {
+				return []Interceptor{
+					func(ctx context.Context, call *Call, next Invoker) (LFMWrapper, error) {
+						lfm, err := next(ctx, call)
+						if err != nil {
+							return lfm, err
+						}
+						lfm.InnerXML = []byte("<user><name>replaced</name></user>")
+						return lfm, nil
+					},
+				}
+			},
+			wantName:  "replaced",
+			wantTries: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body: io.NopCloser(strings.NewReader(
+							`<lfm status="ok"><user><name>testuser</name></user></lfm>`)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Client:    mockClient,
+			}
+
+			var calls []string
+			api.Use(c.interceptors(&calls)...)
+
+			var res lastfm.UserInfo
+			params := struct {
+				User string `url:"user"`
+			}{User: "testuser"}
+			err := api.Get(&res, UserGetInfoMethod, params)
+
+			if mockClient.tries != c.wantTries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+			if c.wantError != nil {
+				if !errors.Is(err, c.wantError) {
+					t.Errorf("expected error %v, got %v", c.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.Name != c.wantName {
+				t.Errorf("expected name %s, got %s", c.wantName, res.Name)
+			}
+			if c.wantURL != "" && mockClient.capturedReq.URL.String() != c.wantURL {
+				t.Errorf("expected URL %s, got %s", c.wantURL, mockClient.capturedReq.URL)
+			}
+			if c.wantCalls != nil && !slices.Equal(calls, c.wantCalls) {
+				t.Errorf("expected calls %v, got %v", c.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestAPI_Logger(t *testing.T) {
+	secrets := []string{
+		"testapikey", "testsecret", "testsessionkey", "testpassword", "testtoken"}
+
+	cases := []struct {
+		name string
+
+		httpMethod string
+		method     APIMethod
+		params     any
+
+		mockBody  string
+		mockError error
+
+		wantLevel []string
+		wantLogs  []string
+	}{
+		{
+			name:       "Signed request",
+			httpMethod: http.MethodGet,
+			method:     UserGetInfoMethod,
+			params: struct {
+				SessionKey string `url:"sk"`
+			}{SessionKey: "testsessionkey"},
+			mockBody:  `<lfm status="ok"><user><name>testuser</name></user></lfm>`,
+			wantLevel: []string{"DEBUG", "DEBUG"},
+			wantLogs:  []string{`"method":"user.getInfo"`, `"status":200`, `"attempt":1`},
+		},
+		{
+			name:       "Mobile session",
+			httpMethod: http.MethodPost,
+			method:     AuthGetMobileSessionMethod,
+			params: struct {
+				Username string `url:"username"`
+				Password string `url:"password"`
+			}{Username: "testuser", Password: "testpassword"},
+			mockBody: `<lfm status="ok"><session><name>testuser</name>` +
+				`<key>testsessionkey</key></session></lfm>`,
+			wantLevel: []string{"DEBUG", "DEBUG"},
+			wantLogs:  []string{`"http_method":"POST"`, `<key>REDACTED</key>`},
+		},
+		{
+			name:       "Auth token",
+			httpMethod: http.MethodGet,
+			method:     AuthGetTokenMethod,
+			mockBody:   `<lfm status="ok"><token>testtoken</token></lfm>`,
+			wantLevel:  []string{"DEBUG", "DEBUG"},
+			wantLogs:   []string{`<token>REDACTED</token>`},
+		},
+		{
+			name:       "Last.fm error",
+			httpMethod: http.MethodGet,
+			method:     UserGetInfoMethod,
+			mockBody:   `<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			wantLevel:  []string{"WARN", "DEBUG"},
+			wantLogs:   []string{`"error_code":6`},
+		},
+		{
+			name:       "Network error",
+			httpMethod: http.MethodGet,
+			method:     UserGetInfoMethod,
+			mockError: &url.Error{
+				Op:  "Get",
+				URL: Endpoint + "?api_key=testapikey&sk=testsessionkey",
+				Err: errNetwork,
+			},
+			wantLevel: []string{"WARN", "DEBUG"},
+			wantLogs:  []string{`api_key=REDACTED`, `network error`},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					if c.mockError != nil {
+						return nil, c.mockError
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(c.mockBody)),
+					}, nil
+				},
+			}
+
+			var buf strings.Builder
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+			}))
+
+			api := &API{
+				APIKey:    "testapikey",
+				Secret:    "testsecret",
+				UserAgent: DefaultUserAgent,
+				Client:    mockClient,
+			}
+			api.SetLogger(logger)
+
+			api.RequestSigned(nil, c.httpMethod, c.method, c.params)
+
+			logs := buf.String()
+			lines := strings.Split(strings.TrimSpace(logs), "\n")
+			if len(lines) != len(c.wantLevel) {
+				t.Fatalf("expected %d log lines, got %d:\n%s", len(c.wantLevel), len(lines), logs)
+			}
+			for i, level := range c.wantLevel {
+				if !strings.Contains(lines[i], `"level":"`+level+`"`) {
+					t.Errorf("line %d: expected level %s, got %s", i, level, lines[i])
+				}
+			}
+			for _, want := range c.wantLogs {
+				if !strings.Contains(logs, want) {
+					t.Errorf("expected logs to contain %s, got:\n%s", want, logs)
+				}
+			}
+			for _, secret := range secrets {
+				if strings.Contains(logs, secret) {
+					t.Errorf("logs contain unredacted %q:\n%s", secret, logs)
+				}
+			}
+		})
+	}
+}
+
+type mockTracer struct {
+	started []APIMethod
+	ended   []CallStats
+}
+
+func (m *mockTracer) StartSpan(ctx context.Context, method APIMethod) (context.Context, Span) {
+	m.started = append(m.started, method)
+	return ctx, m
+}
+
+func (m *mockTracer) End(stats CallStats) {
+	m.ended = append(m.ended, stats)
+}
+
+func TestAPI_Telemetry(t *testing.T) {
+	cases := []struct {
+		name string
+
+		mockStatusCodes []int
+		mockBodies      []string
+
+		wantStats   CallStats
+		wantMetrics []string
+	}{
+		{
+			name:            "Success",
+			mockStatusCodes: []int{http.StatusOK},
+			mockBodies:      []string{`<lfm status="ok"><user><name>testuser</name></user></lfm>`},
+			wantStats: CallStats{
+				Method:     UserGetInfoMethod,
+				StatusCode: http.StatusOK,
+				Attempts:   1,
+			},
+			wantMetrics: []string{
+				`lastfm_calls_total{method="user.getInfo",result="ok"} 1`,
+				`lastfm_call_duration_seconds_bucket{method="user.getInfo",le="+Inf"} 1`,
+				`lastfm_call_duration_seconds_count{method="user.getInfo"} 1`,
+			},
+		},
+		{
+			name:            "Retried error",
+			mockStatusCodes: []int{http.StatusOK, http.StatusOK},
+			mockBodies: []string{
+				`<lfm status="failed"><error code="8">Operation failed</error></lfm>`,
+				`<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			},
+			wantStats: CallStats{
+				Method:     UserGetInfoMethod,
+				StatusCode: http.StatusOK,
+				Attempts:   2,
+				ErrorCode:  ErrInvalidParameters,
+			},
+			wantMetrics: []string{
+				`lastfm_calls_total{method="user.getInfo",result="error"} 1`,
+				`lastfm_errors_total{method="user.getInfo",code="6"} 1`,
+				`lastfm_retries_total{method="user.getInfo"} 1`,
+			},
+		},
+		{
+			name:            "HTTP error",
+			mockStatusCodes: []int{http.StatusNotFound},
+			mockBodies:      []string{""},
+			wantStats: CallStats{
+				Method:     UserGetInfoMethod,
+				StatusCode: http.StatusNotFound,
+				Attempts:   1,
+			},
+			wantMetrics: []string{
+				`lastfm_errors_total{method="user.getInfo",code="http_404"} 1`,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{}
+			mockClient.doFunc = func(req *http.Request) (*http.Response, error) {
+				i := mockClient.tries - 1
+				return &http.Response{
+					StatusCode: c.mockStatusCodes[i],
+					Body:       io.NopCloser(strings.NewReader(c.mockBodies[i])),
+				}, nil
+			}
+
+			tracer := &mockTracer{}
+			metrics := NewPrometheusMetrics()
+
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Retries:   DefaultRetries,
+				Client:    mockClient,
+			}
+			api.SetTracer(tracer)
+			api.SetMetrics(metrics)
+
+			err := api.Get(nil, UserGetInfoMethod, nil)
+
+			if len(tracer.started) != 1 || len(tracer.ended) != 1 {
+				t.Fatalf("expected 1 span, got %d started and %d ended",
+					len(tracer.started), len(tracer.ended))
+			}
+
+			got := tracer.ended[0]
+			if got.Err != err {
+				t.Errorf("expected error %v, got %v", err, got.Err)
+			}
+			got.Err, got.Duration = nil, 0
+			if got != c.wantStats {
+				t.Errorf("expected stats %+v, got %+v", c.wantStats, got)
+			}
+
+			rec := httptest.NewRecorder()
+			metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			body := rec.Body.String()
+			for _, want := range c.wantMetrics {
+				if !strings.Contains(body, want+"\n") {
+					t.Errorf("expected metrics to contain %s, got:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	cases := []struct {
+		name string
+
+		err error
+
+		wantSentinel  error
+		wantAuth      bool
+		wantTransient bool
+		wantPermanent bool
+	}{
+		{
+			name:          "Invalid session key",
+			err:           NewLastFMError(ErrInvalidSessionKey, "Invalid session key - Please re-authenticate"),
+			wantSentinel:  ErrInvalidSessionKeyError,
+			wantAuth:      true,
+			wantPermanent: true,
+		},
+		{
+			name:          "Invalid parameters",
+			err:           fmt.Errorf("wrapped: %w", NewLastFMError(ErrInvalidParameters, "")),
+			wantSentinel:  ErrInvalidParametersError,
+			wantPermanent: true,
+		},
+		{
+			name: "Service unavailable",
+			err: NewLastFMError(ErrServiceUnavailable, "").
+				WrapHTTPError(&HTTPError{StatusCode: http.StatusServiceUnavailable}),
+			wantSentinel:  ErrServiceUnavailableError,
+			wantTransient: true,
+		},
+		{
+			name:          "Service offline",
+			err:           NewLastFMError(ErrServiceOffline, ""),
+			wantSentinel:  ErrServiceOfflineError,
+			wantTransient: true,
+		},
+		{
+			name:          "Session required",
+			err:           NewLastFMError(ErrSessionRequired, SessionRequiredMessage),
+			wantSentinel:  ErrSessionRequiredError,
+			wantAuth:      true,
+			wantPermanent: true,
+		},
+		{
+			name:          "HTTP 502",
+			err:           &HTTPError{StatusCode: http.StatusBadGateway},
+			wantTransient: true,
+		},
+		{
+			name:          "HTTP 404",
+			err:           &HTTPError{StatusCode: http.StatusNotFound},
+			wantPermanent: true,
+		},
+		{
+			name:          "Network error",
+			err:           &url.Error{Op: "Get", URL: Endpoint, Err: syscall.ECONNRESET},
+			wantTransient: true,
+		},
+		{
+			name: "Context cancelled",
+			err:  context.Canceled,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if c.wantSentinel != nil && !errors.Is(c.err, c.wantSentinel) {
+				t.Errorf("expected error to match %v", c.wantSentinel)
+			}
+			if c.wantSentinel != ErrOperationFailedError && errors.Is(c.err, ErrOperationFailedError) {
+				t.Errorf("expected error not to match %v", ErrOperationFailedError)
+			}
+			if got := IsAuthError(c.err); got != c.wantAuth {
+				t.Errorf("expected IsAuthError %v, got %v", c.wantAuth, got)
+			}
+			if got := IsTransient(c.err); got != c.wantTransient {
+				t.Errorf("expected IsTransient %v, got %v", c.wantTransient, got)
+			}
+			if got := IsPermanent(c.err); got != c.wantPermanent {
+				t.Errorf("expected IsPermanent %v, got %v", c.wantPermanent, got)
+			}
+		})
+	}
+}
+
+func TestAPI_ErrorDiagnostics(t *testing.T) {
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(strings.NewReader(
+					`<lfm status="failed"><error code="8">Operation failed</error></lfm>`)),
+			}, nil
+		},
+	}
+
+	api := &API{
+		APIKey:    "testapikey",
+		UserAgent: DefaultUserAgent,
+		Retries:   2,
+		Client:    mockClient,
+	}
+
+	err := api.Get(nil, ArtistGetInfoMethod, nil)
+
+	var lferr *LastFMError
+	if !errors.As(err, &lferr) {
+		t.Fatalf("expected *LastFMError, got %v", err)
+	}
+	if lferr.Method != ArtistGetInfoMethod {
+		t.Errorf("expected method %s, got %s", ArtistGetInfoMethod, lferr.Method)
+	}
+	if lferr.Attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", lferr.Attempts)
+	}
+}
+
+func TestAPI_WithResponse(t *testing.T) {
+	cases := []struct {
+		name string
+
+		withResponse bool
+		capture      bool
+		mockBody     string
+
+		wantBody     string
+		wantError    bool
+		wantAttached bool
+	}{
+		{
+			name:         "Success",
+			withResponse: true,
+			mockBody:     `<lfm status="ok"><user><name>testuser</name></user></lfm>`,
+			wantBody:     `<lfm status="ok"><user><name>testuser</name></user></lfm>`,
+		},
+		{
+			name:         "Error",
+			withResponse: true,
+			mockBody:     `<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			wantBody:     `<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			wantError:    true,
+			wantAttached: true,
+		},
+		{
+			name:         "Captured error",
+			capture:      true,
+			mockBody:     `<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			wantError:    true,
+			wantAttached: true,
+		},
+		{
+			name:      "Not captured",
+			mockBody:  `<lfm status="failed"><error code="6">User not found</error></lfm>`,
+			wantError: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"X-Ratelimit-Remaining": {"42"}},
+						Body:       io.NopCloser(strings.NewReader(c.mockBody)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:           "testapikey",
+				UserAgent:        DefaultUserAgent,
+				CaptureResponses: c.capture,
+				Client:           mockClient,
+			}
+
+			ctx := context.Background()
+			var info ResponseInfo
+			if c.withResponse {
+				ctx = WithResponse(ctx, &info)
+			}
+
+			err := api.GetContext(ctx, nil, UserGetInfoMethod, nil)
+			if (err != nil) != c.wantError {
+				t.Fatalf("expected error %v, got %v", c.wantError, err)
+			}
+
+			var lferr *LastFMError
+			if errors.As(err, &lferr) && (lferr.Response != nil) != c.wantAttached {
+				t.Errorf("expected attached response %v, got %v", c.wantAttached, lferr.Response)
+			}
+			if c.wantAttached && string(lferr.Response.Body) != c.mockBody {
+				t.Errorf("expected attached body %s, got %s", c.mockBody, lferr.Response.Body)
+			}
+
+			if !c.withResponse {
+				return
+			}
+			if string(info.Body) != c.wantBody {
+				t.Errorf("expected body %s, got %s", c.wantBody, info.Body)
+			}
+			if info.StatusCode != http.StatusOK || info.Attempts != 1 {
+				t.Errorf("expected status 200 and 1 attempt, got %d and %d",
+					info.StatusCode, info.Attempts)
+			}
+			if got := info.Header.Get("X-RateLimit-Remaining"); got != "42" {
+				t.Errorf("expected rate limit header 42, got %q", got)
+			}
+			wantURL := Endpoint + "?api_key=REDACTED&method=user.getInfo"
+			if info.URL != wantURL {
+				t.Errorf("expected URL %s, got %s", wantURL, info.URL)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	offline := NewLastFMError(ErrServiceOffline, "Service offline")
+	unavailable := NewLastFMError(ErrServiceUnavailable, "Service temporarily unavailable")
+	notFound := NewLastFMError(ErrInvalidParameters, "User not found")
+
+	cases := []struct {
+		name string
+
+		zero        bool
+		minRequests int
+		results     []error
+		step        time.Duration
+
+		wantState BreakerState
+	}{
+		{
+			name:        "Opens at failure ratio",
+			minRequests: 4,
+			results:     []error{nil, nil, offline, unavailable},
+			wantState:   BreakerOpen,
+		},
+		{
+			name:        "Stays closed below failure ratio",
+			minRequests: 4,
+			results:     []error{nil, nil, nil, offline},
+			wantState:   BreakerClosed,
+		},
+		{
+			name:        "Stays closed below minimum requests",
+			minRequests: 4,
+			results:     []error{offline, offline, offline},
+			wantState:   BreakerClosed,
+		},
+		{
+			name:        "Permanent errors aren't failures",
+			minRequests: 2,
+			results:     []error{notFound, notFound, notFound},
+			wantState:   BreakerClosed,
+		},
+		{
+			name:        "Context errors aren't counted",
+			minRequests: 2,
+			results:     []error{offline, context.Canceled, context.DeadlineExceeded},
+			wantState:   BreakerClosed,
+		},
+		{
+			name:      "Zero value stays closed on success",
+			zero:      true,
+			results:   []error{nil},
+			wantState: BreakerClosed,
+		},
+		{
+			name:      "Zero value opens with default settings",
+			zero:      true,
+			results:   slices.Repeat([]error{offline}, DefaultBreakerMinRequests),
+			step:      time.Second,
+			wantState: BreakerOpen,
+		},
+		{
+			name:        "Failures expire with the window",
+			minRequests: 3,
+			results:     []error{offline, offline, offline, offline},
+			step:        40 * time.Second,
+			wantState:   BreakerClosed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			now := time.Now()
+
+			b := NewCircuitBreaker()
+			b.MinRequests = c.minRequests
+			if c.zero {
+				b = &CircuitBreaker{}
+			}
+			b.now = func() time.Time { return now }
+
+			for _, err := range c.results {
+				if aerr := b.Allow(); aerr != nil {
+					t.Fatalf("unexpected error: %v", aerr)
+				}
+				b.Record(err)
+				now = now.Add(c.step)
+			}
+
+			if state := b.State(); state != c.wantState {
+				t.Errorf("expected state %s, got %s", c.wantState, state)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	offline := NewLastFMError(ErrServiceOffline, "Service offline")
+	now := time.Now()
+
+	var transitions []string
+
+	b := NewCircuitBreaker()
+	b.MinRequests = 1
+	b.now = func() time.Time { return now }
+	b.OnStateChange = func(from, to BreakerState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+
+	b.Allow()
+	b.Record(offline)
+
+	var cerr *CircuitOpenError
+	if err := b.Allow(); !errors.As(err, &cerr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected *CircuitOpenError, got %v", err)
+	}
+	if want := now.Add(b.OpenTimeout); !cerr.Until.Equal(want) {
+		t.Errorf("expected open until %s, got %s", want, cerr.Until)
+	}
+
+	now = now.Add(b.OpenTimeout)
+	if state := b.State(); state != BreakerHalfOpen {
+		t.Errorf("expected state %s, got %s", BreakerHalfOpen, state)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected second probe to be rejected, got %v", err)
+	}
+	b.Record(offline)
+
+	now = now.Add(b.OpenTimeout)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	b.Record(nil)
+
+	want := []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}
+	if !slices.Equal(transitions, want) {
+		t.Errorf("expected transitions %v, got %v", want, transitions)
+	}
+}
+
+func TestAPI_Breaker(t *testing.T) {
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(strings.NewReader(
+					`<lfm status="failed"><error code="16">Service temporarily unavailable</error></lfm>`)),
+			}, nil
+		},
+	}
+
+	b := NewCircuitBreaker()
+	b.MinRequests = 2
+
+	api := &API{
+		APIKey:    "testapikey",
+		UserAgent: DefaultUserAgent,
+		Retries:   DefaultRetries,
+		Breaker:   b,
+		Client:    mockClient,
+	}
+
+	err := api.Get(nil, UserGetInfoMethod, nil)
+	if !errors.Is(err, ErrServiceUnavailableError) {
+		t.Errorf("expected error %v, got %v", ErrServiceUnavailableError, err)
+	}
+	if mockClient.tries != 2 {
+		t.Errorf("expected 2 tries, got %d", mockClient.tries)
+	}
+
+	err = api.Get(nil, UserGetInfoMethod, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected error %v, got %v", ErrCircuitOpen, err)
+	}
+	if mockClient.tries != 2 {
+		t.Errorf("expected no more tries, got %d", mockClient.tries)
+	}
+}
+
+func TestAPI_CoalescedResponse(t *testing.T) {
+	const body = `<lfm status="failed"><error code="6">Artist not found</error></lfm>`
+
+	release := make(chan struct{})
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			<-release
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		},
+	}
+	api := &API{
+		APIKey:    "testapikey",
+		UserAgent: DefaultUserAgent,
+		Coalesce:  true,
+		Client:    mockClient,
+	}
+
+	waitFor := func(waiters int) {
+		for {
+			api.inflight.mu.Lock()
+			n := 0
+			for _, f := range api.inflight.calls {
+				n += f.waiters
+			}
+			api.inflight.mu.Unlock()
+
+			if n >= waiters {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	params := lastfm.ArtistInfoParams{Artist: "Artist"}
+
+	// The first caller, which sends the request, doesn't capture responses.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		api.Get(nil, ArtistGetInfoMethod, params)
+	}()
+	waitFor(1)
+
+	var info ResponseInfo
+	var err error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ctx := WithResponse(context.Background(), &info)
+		err = api.GetContext(ctx, nil, ArtistGetInfoMethod, params)
+	}()
+	waitFor(2)
+
+	close(release)
+	wg.Wait()
+
+	if mockClient.tries != 1 {
+		t.Errorf("expected 1 try, got %d", mockClient.tries)
+	}
+	if string(info.Body) != body {
+		t.Errorf("expected body %s, got %s", body, info.Body)
+	}
+
+	var lferr *LastFMError
+	if !errors.As(err, &lferr) {
+		t.Fatalf("expected *LastFMError, got %v", err)
+	}
+	if lferr.Response == nil || string(lferr.Response.Body) != body {
+		t.Errorf("expected attached response with body %s, got %+v", body, lferr.Response)
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	now := time.Now()
+
+	memory := NewMemoryCacheStore(2)
+	memory.now = func() time.Time { return now }
+
+	disk, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	disk.now = func() time.Time { return now }
+
+	cases := []struct {
+		name  string
+		store CacheStore
+		lru   bool
+	}{
+		{name: "Memory", store: memory, lru: true},
+		{name: "Disk", store: disk},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.store
+
+			s.Set("a", []byte("1"), time.Minute)
+			s.Set("b", []byte("2"), time.Hour)
+			if v, ok := s.Get("a"); !ok || string(v) != "1" {
+				t.Errorf("expected value 1, got %q", v)
+			}
+
+			s.Set("c", []byte("3"), time.Hour)
+			if _, ok := s.Get("b"); ok == c.lru {
+				t.Errorf("expected evicted %v, got %v", c.lru, !ok)
+			}
+
+			now = now.Add(2 * time.Minute)
+			if _, ok := s.Get("a"); ok {
+				t.Errorf("expected expired value")
+			}
+
+			s.DeleteFunc(func(key string) bool { return key == "c" })
+			if _, ok := s.Get("c"); ok {
+				t.Errorf("expected deleted value")
+			}
+		})
+	}
+}
+
+func TestUser_RecentTracksAll(t *testing.T) {
+	pages := map[string]string{
+		"1": `<lfm status="ok"><recenttracks user="testuser" page="1" totalPages="2">` +
+			`<track nowplaying="true"><name>Playing</name></track>` +
+			`<track><name>First</name></track><track><name>Second</name></track>` +
+			`</recenttracks></lfm>`,
+		"2": `<lfm status="ok"><recenttracks user="testuser" page="2" totalPages="2">` +
+			`<track nowplaying="true"><name>Playing</name></track>` +
+			`<track><name>Third</name></track>` +
+			`</recenttracks></lfm>`,
+	}
+
+	cases := []struct {
+		name string
+
+		failPage    string
+		pageIgnored bool
+		stopAt      int
+
+		wantTitles []string
+		wantError  error
+		wantTries  uint
+	}{
+		{
+			name:       "All pages",
+			wantTitles: []string{"Playing", "First", "Second", "Third"},
+			wantTries:  2,
+		},
+		{
+			name:       "Stop early",
+			stopAt:     2,
+			wantTitles: []string{"Playing", "First"},
+			wantTries:  1,
+		},
+		{
+			name:       "Error on later page",
+			failPage:   "2",
+			wantTitles: []string{"Playing", "First", "Second"},
+			wantError:  &LastFMError{Code: ErrInvalidParameters},
+			wantTries:  2,
+		},
+		{
+			name:        "Page ignored",
+			pageIgnored: true,
+			wantTitles:  []string{"Playing", "First", "Second"},
+			wantTries:   2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					page := req.URL.Query().Get("page")
+					body := pages[page]
+					if c.pageIgnored {
+						// always respond with the first of 3 pages
+						body = strings.Replace(pages["1"], `totalPages="2"`, `totalPages="3"`, 1)
+					}
+					if page == c.failPage {
+						body = `<lfm status="failed"><error code="6">Invalid parameters</error></lfm>`
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				UserAgent: DefaultUserAgent,
+				Client:    mockClient,
+			}
+
+			var titles []string
+			var err error
+			params := lastfm.RecentTracksParams{User: "testuser"}
+			for track, terr := range NewUser(api).RecentTracksAll(params) {
+				if terr != nil {
+					err = terr
+					break
+				}
+				titles = append(titles, track.Title)
+				if len(titles) == c.stopAt {
+					break
+				}
+			}
+
+			if strings.Join(titles, ",") != strings.Join(c.wantTitles, ",") {
+				t.Errorf("expected titles %v, got %v", c.wantTitles, titles)
+			}
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+			if c.wantTries != mockClient.tries {
+				t.Errorf("expected %d tries, got %d", c.wantTries, mockClient.tries)
+			}
+		})
+	}
+}
+
+func TestAPI_SetService(t *testing.T) {
+	cases := []struct {
+		name    string
+		service *Service
+
+		wantEndpoint string
+		wantAuthURL  string
+	}{
+		{
+			name:         "Default",
+			wantEndpoint: "https://ws.audioscrobbler.com/2.0/",
+			wantAuthURL:  "https://www.last.fm/api/auth?api_key=testapikey&token=testtoken",
+		},
+		{
+			name:         "Libre.fm",
+			service:      &LibreFM,
+			wantEndpoint: "https://libre.fm/2.0/",
+			wantAuthURL:  "https://libre.fm/api/auth/?api_key=testapikey&token=testtoken",
+		},
+		{
+			name:         "GNU FM",
+			service:      &Service{Endpoint: "https://gnufm.example.com/2.0/"},
+			wantEndpoint: "https://gnufm.example.com/2.0/",
+			wantAuthURL:  "https://www.last.fm/api/auth?api_key=testapikey&token=testtoken",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var gotURLs []string
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					u := *req.URL
+					u.RawQuery = ""
+					gotURLs = append(gotURLs, u.String())
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(`<lfm status="ok"></lfm>`)),
+					}, nil
+				},
+			}
+
+			api := &API{
+				APIKey:    "testapikey",
+				Secret:    "testsecret",
+				UserAgent: DefaultUserAgent,
+				Client:    mockClient,
+			}
+			if c.service != nil {
+				api.SetService(*c.service)
+			}
+
+			if err := api.Get(nil, UserGetInfoMethod, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := api.PostSigned(nil, TrackLoveMethod, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, got := range gotURLs {
+				if got != c.wantEndpoint {
+					t.Errorf("expected endpoint %s, got %s", c.wantEndpoint, got)
+				}
+			}
+			if got := api.AuthTokenURL("testtoken"); got != c.wantAuthURL {
+				t.Errorf("expected auth URL %s, got %s", c.wantAuthURL, got)
+			}
+		})
+	}
+}
+
 func TestSignature(t *testing.T) {
 	cases := []struct {
 		name       string
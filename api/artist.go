@@ -1,6 +1,11 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+	"iter"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Artist struct {
 	api *API
@@ -13,107 +18,274 @@ func NewArtist(api *API) *Artist {
 
 // Correction returns the artist name corrections of an artist.
 func (a Artist) Correction(artist string) (*lastfm.ArtistCorrection, error) {
+	return a.CorrectionContext(context.Background(), artist)
+}
+
+// CorrectionContext is like Correction but uses the given context for the
+// request.
+func (a Artist) CorrectionContext(
+	ctx context.Context, artist string) (*lastfm.ArtistCorrection, error) {
+
 	var res lastfm.ArtistCorrection
 	p := lastfm.ArtistCorrectionParams{Artist: artist}
-	return &res, a.api.Get(&res, ArtistGetCorrectionMethod, p)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetCorrectionMethod, p)
 }
 
 // Info returns the information of an artist by artist name.
 func (a Artist) Info(params lastfm.ArtistInfoParams) (*lastfm.ArtistInfo, error) {
+	return a.InfoContext(context.Background(), params)
+}
+
+// InfoContext is like Info but uses the given context for the request.
+func (a Artist) InfoContext(
+	ctx context.Context, params lastfm.ArtistInfoParams) (*lastfm.ArtistInfo, error) {
+
 	var res lastfm.ArtistInfo
-	return &res, a.api.Get(&res, ArtistGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetInfoMethod, params)
 }
 
 // InfoByMBID returns the information of an artist by MBID.
 func (a Artist) InfoByMBID(params lastfm.ArtistInfoMBIDParams) (*lastfm.ArtistInfo, error) {
+	return a.InfoByMBIDContext(context.Background(), params)
+}
+
+// InfoByMBIDContext is like InfoByMBID but uses the given context for the
+// request.
+func (a Artist) InfoByMBIDContext(
+	ctx context.Context, params lastfm.ArtistInfoMBIDParams) (*lastfm.ArtistInfo, error) {
+
 	var res lastfm.ArtistInfo
-	return &res, a.api.Get(&res, ArtistGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetInfoMethod, params)
 }
 
 // UserInfo returns the information of an artist for user by artist name.
 func (a Artist) UserInfo(params lastfm.ArtistUserInfoParams) (*lastfm.ArtistUserInfo, error) {
+	return a.UserInfoContext(context.Background(), params)
+}
+
+// UserInfoContext is like UserInfo but uses the given context for the request.
+func (a Artist) UserInfoContext(
+	ctx context.Context, params lastfm.ArtistUserInfoParams) (*lastfm.ArtistUserInfo, error) {
+
 	var res lastfm.ArtistUserInfo
-	return &res, a.api.Get(&res, ArtistGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetInfoMethod, params)
 }
 
 // UserInfoByMBID returns the information of an artist for user by MBID.
 func (a Artist) UserInfoByMBID(
 	params lastfm.ArtistUserInfoMBIDParams) (*lastfm.ArtistUserInfo, error) {
+	return a.UserInfoByMBIDContext(context.Background(), params)
+}
+
+// UserInfoByMBIDContext is like UserInfoByMBID but uses the given context for
+// the request.
+func (a Artist) UserInfoByMBIDContext(
+	ctx context.Context, params lastfm.ArtistUserInfoMBIDParams) (*lastfm.ArtistUserInfo, error) {
 
 	var res lastfm.ArtistUserInfo
-	return &res, a.api.Get(&res, ArtistGetInfoMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetInfoMethod, params)
 }
 
 // Similar returns the similar artists of an artist by artist name.
 func (a Artist) Similar(params lastfm.ArtistSimilarParams) (*lastfm.SimilarArtists, error) {
+	return a.SimilarContext(context.Background(), params)
+}
+
+// SimilarContext is like Similar but uses the given context for the request.
+func (a Artist) SimilarContext(
+	ctx context.Context, params lastfm.ArtistSimilarParams) (*lastfm.SimilarArtists, error) {
+
 	var res lastfm.SimilarArtists
-	return &res, a.api.Get(&res, ArtistGetSimilarMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetSimilarMethod, params)
 }
 
 // SimilarByMBID returns the similar artists of an artist by MBID.
 func (a Artist) SimilarByMBID(
 	params lastfm.ArtistSimilarMBIDParams) (*lastfm.SimilarArtists, error) {
+	return a.SimilarByMBIDContext(context.Background(), params)
+}
+
+// SimilarByMBIDContext is like SimilarByMBID but uses the given context for the
+// request.
+func (a Artist) SimilarByMBIDContext(
+	ctx context.Context, params lastfm.ArtistSimilarMBIDParams) (*lastfm.SimilarArtists, error) {
 
 	var res lastfm.SimilarArtists
-	return &res, a.api.Get(&res, ArtistGetSimilarMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetSimilarMethod, params)
 }
 
 // UserTags returns the tags of an artist for user by artist name.
 func (a Artist) UserTags(params lastfm.ArtistTagsParams) (*lastfm.ArtistTags, error) {
+	return a.UserTagsContext(context.Background(), params)
+}
+
+// UserTagsContext is like UserTags but uses the given context for the request.
+func (a Artist) UserTagsContext(
+	ctx context.Context, params lastfm.ArtistTagsParams) (*lastfm.ArtistTags, error) {
+
 	var res lastfm.ArtistTags
-	return &res, a.api.Get(&res, ArtistGetTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTagsMethod, params)
 }
 
 // UserTagsByMBID returns the tags of an artist for user by MBID.
 func (a Artist) UserTagsByMBID(params lastfm.ArtistTagsMBIDParams) (*lastfm.ArtistTags, error) {
+	return a.UserTagsByMBIDContext(context.Background(), params)
+}
+
+// UserTagsByMBIDContext is like UserTagsByMBID but uses the given context for
+// the request.
+func (a Artist) UserTagsByMBIDContext(
+	ctx context.Context, params lastfm.ArtistTagsMBIDParams) (*lastfm.ArtistTags, error) {
+
 	var res lastfm.ArtistTags
-	return &res, a.api.Get(&res, ArtistGetTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTagsMethod, params)
 }
 
 // TopAlbums returns the top albums of an artist by artist name.
 func (a Artist) TopAlbums(params lastfm.ArtistTopAlbumsParams) (*lastfm.ArtistTopAlbums, error) {
+	return a.TopAlbumsContext(context.Background(), params)
+}
+
+// TopAlbumsContext is like TopAlbums but uses the given context for the
+// request.
+func (a Artist) TopAlbumsContext(
+	ctx context.Context, params lastfm.ArtistTopAlbumsParams) (*lastfm.ArtistTopAlbums, error) {
+
 	var res lastfm.ArtistTopAlbums
-	return &res, a.api.Get(&res, ArtistGetTopAlbumsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopAlbumsMethod, params)
+}
+
+// TopAlbumsAll returns an iterator over the top albums of an artist on every
+// page, starting from params.Page.
+func (a Artist) TopAlbumsAll(
+	params lastfm.ArtistTopAlbumsParams) iter.Seq2[lastfm.ArtistTopAlbum, error] {
+
+	return a.TopAlbumsAllContext(context.Background(), params)
+}
+
+// TopAlbumsAllContext is like TopAlbumsAll but uses the given context for the
+// requests.
+func (a Artist) TopAlbumsAllContext(
+	ctx context.Context, params lastfm.ArtistTopAlbumsParams) iter.Seq2[lastfm.ArtistTopAlbum, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.ArtistTopAlbums, error) {
+		p := params
+		p.Page = page
+		return a.TopAlbumsContext(ctx, p)
+	}
+	items := func(res *lastfm.ArtistTopAlbums) ([]lastfm.ArtistTopAlbum, int, int) {
+		return res.Albums, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopAlbumsByMBID returns the top albums of an artist by MBID.
 func (a Artist) TopAlbumsByMBID(
 	params lastfm.ArtistTopAlbumsMBIDParams) (*lastfm.ArtistTopAlbums, error) {
+	return a.TopAlbumsByMBIDContext(context.Background(), params)
+}
+
+// TopAlbumsByMBIDContext is like TopAlbumsByMBID but uses the given context for
+// the request.
+func (a Artist) TopAlbumsByMBIDContext(
+	ctx context.Context, params lastfm.ArtistTopAlbumsMBIDParams) (*lastfm.ArtistTopAlbums, error) {
 
 	var res lastfm.ArtistTopAlbums
-	return &res, a.api.Get(&res, ArtistGetTopAlbumsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopAlbumsMethod, params)
 }
 
 // TopTracks returns the top tracks of an artist by artist name.
 func (a Artist) TopTags(params lastfm.ArtistTopTagsParams) (*lastfm.ArtistTopTags, error) {
+	return a.TopTagsContext(context.Background(), params)
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (a Artist) TopTagsContext(
+	ctx context.Context, params lastfm.ArtistTopTagsParams) (*lastfm.ArtistTopTags, error) {
+
 	var res lastfm.ArtistTopTags
-	return &res, a.api.Get(&res, ArtistGetTopTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopTagsMethod, params)
 }
 
 // TopTagsByMBID returns the top tracks of an artist by MBID.
 func (a Artist) TopTagsByMBID(
 	params lastfm.ArtistTopTagsMBIDParams) (*lastfm.ArtistTopTags, error) {
+	return a.TopTagsByMBIDContext(context.Background(), params)
+}
+
+// TopTagsByMBIDContext is like TopTagsByMBID but uses the given context for the
+// request.
+func (a Artist) TopTagsByMBIDContext(
+	ctx context.Context, params lastfm.ArtistTopTagsMBIDParams) (*lastfm.ArtistTopTags, error) {
 
 	var res lastfm.ArtistTopTags
-	return &res, a.api.Get(&res, ArtistGetTopTagsMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopTagsMethod, params)
 }
 
 // TopTracks returns the top tracks of an artist by artist name.
 func (a Artist) TopTracks(params lastfm.ArtistTopTracksParams) (*lastfm.ArtistTopTracks, error) {
+	return a.TopTracksContext(context.Background(), params)
+}
+
+// TopTracksContext is like TopTracks but uses the given context for the
+// request.
+func (a Artist) TopTracksContext(
+	ctx context.Context, params lastfm.ArtistTopTracksParams) (*lastfm.ArtistTopTracks, error) {
+
 	var res lastfm.ArtistTopTracks
-	return &res, a.api.Get(&res, ArtistGetTopTracksMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopTracksMethod, params)
+}
+
+// TopTracksAll returns an iterator over the top tracks of an artist on every
+// page, starting from params.Page.
+func (a Artist) TopTracksAll(
+	params lastfm.ArtistTopTracksParams) iter.Seq2[lastfm.ArtistTopTrack, error] {
+
+	return a.TopTracksAllContext(context.Background(), params)
+}
+
+// TopTracksAllContext is like TopTracksAll but uses the given context for the
+// requests.
+func (a Artist) TopTracksAllContext(
+	ctx context.Context, params lastfm.ArtistTopTracksParams) iter.Seq2[lastfm.ArtistTopTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.ArtistTopTracks, error) {
+		p := params
+		p.Page = page
+		return a.TopTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.ArtistTopTracks) ([]lastfm.ArtistTopTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopTracksByMBID returns the top tracks of an artist by MBID.
 func (a Artist) TopTracksByMBID(
 	params lastfm.ArtistTopTracksMBIDParams) (*lastfm.ArtistTopTracks, error) {
+	return a.TopTracksByMBIDContext(context.Background(), params)
+}
+
+// TopTracksByMBIDContext is like TopTracksByMBID but uses the given context for
+// the request.
+func (a Artist) TopTracksByMBIDContext(
+	ctx context.Context, params lastfm.ArtistTopTracksMBIDParams) (*lastfm.ArtistTopTracks, error) {
 
 	var res lastfm.ArtistTopTracks
-	return &res, a.api.Get(&res, ArtistGetTopTracksMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistGetTopTracksMethod, params)
 }
 
 // Search returns the results of an album search.
 func (a Artist) Search(params lastfm.ArtistSearchParams) (*lastfm.ArtistSearchResult, error) {
+	return a.SearchContext(context.Background(), params)
+}
+
+// SearchContext is like Search but uses the given context for the request.
+func (a Artist) SearchContext(
+	ctx context.Context, params lastfm.ArtistSearchParams) (*lastfm.ArtistSearchResult, error) {
+
 	var res lastfm.ArtistSearchResult
-	return &res, a.api.Get(&res, ArtistSearchMethod, params)
+	return &res, a.api.GetContext(ctx, &res, ArtistSearchMethod, params)
 }
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+
 	"github.com/twoscott/gobble-fm/lastfm"
 )
 
@@ -24,8 +26,13 @@ func NewAuth(api *API) *Auth {
 // AuthGetToken method of the Last.fm API. The token is cached in the session,
 // so subsequent calls to this method will return the same cached token.
 func (a Auth) Token() (string, error) {
+	return a.TokenContext(context.Background())
+}
+
+// TokenContext is like Token but uses the given context for the request.
+func (a Auth) TokenContext(ctx context.Context) (string, error) {
 	var token string
-	err := a.api.GetSigned(&token, AuthGetTokenMethod, nil)
+	err := a.api.GetSignedContext(ctx, &token, AuthGetTokenMethod, nil)
 	return token, err
 }
 
@@ -33,16 +40,29 @@ func (a Auth) Token() (string, error) {
 // authenticate requests to the Last.fm API. The session is obtained by calling
 // the AuthGetSession method of the Last.fm API.
 func (a Auth) Session(token string) (*lastfm.Session, error) {
+	return a.SessionContext(context.Background(), token)
+}
+
+// SessionContext is like Session but uses the given context for the request.
+func (a Auth) SessionContext(ctx context.Context, token string) (*lastfm.Session, error) {
 	var res lastfm.Session
 	p := lastfm.SessionParams{Token: token}
-	return &res, a.api.PostSigned(&res, AuthGetSessionMethod, p)
+	return &res, a.api.PostSignedContext(ctx, &res, AuthGetSessionMethod, p)
 }
 
 // MobileSession returns a session for the given mobile user credentials. This
 // session can be used to authenticate requests to the Last.fm API. The session
 // is obtained by calling the AuthGetMobileSession method of the Last.fm API.
 func (a Auth) MobileSession(username, password string) (*lastfm.Session, error) {
+	return a.MobileSessionContext(context.Background(), username, password)
+}
+
+// MobileSessionContext is like MobileSession but uses the given context for the
+// request.
+func (a Auth) MobileSessionContext(
+	ctx context.Context, username, password string) (*lastfm.Session, error) {
+
 	var res lastfm.Session
 	p := lastfm.MobileSessionParams{Username: username, Password: password}
-	return &res, a.api.PostSigned(&res, AuthGetMobileSessionMethod, p)
+	return &res, a.api.PostSignedContext(ctx, &res, AuthGetMobileSessionMethod, p)
 }
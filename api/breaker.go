@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerFailureRatio     = 0.5
+	DefaultBreakerMinRequests      = 10
+	DefaultBreakerWindow           = time.Minute
+	DefaultBreakerOpenTimeout      = 30 * time.Second
+	DefaultBreakerHalfOpenRequests = 1
+)
+
+// ErrCircuitOpen is matched by errors returned when a CircuitBreaker is open,
+// using errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned for requests rejected by an open
+// CircuitBreaker, without being sent.
+type CircuitOpenError struct {
+	// Until is when the breaker will allow a probe request again.
+	Until time.Time
+}
+
+// Error implements the error interface.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrCircuitOpen, e.Until.Format(time.RFC3339))
+}
+
+// Is reports whether the target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through, and counts their failures.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all requests until its open timeout has passed.
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe requests through, and
+	// closes if they succeed, or opens again if any fail.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker stops requests from being sent during sustained outages of
+// the API, so they fail fast with a *CircuitOpenError instead of going through
+// every retry. Every request attempt, including retries, is counted.
+//
+// The breaker opens when the ratio of failed attempts within a window reaches
+// FailureRatio, once at least MinRequests attempts were made. After
+// OpenTimeout, it lets HalfOpenRequests probe attempts through, and closes if
+// they all succeed, or opens again if any fail.
+//
+// Zero fields use the default settings, so the zero value is ready to use. A
+// CircuitBreaker is safe for concurrent use, and may be shared between API
+// clients.
+type CircuitBreaker struct {
+	// FailureRatio is the ratio of failed attempts in the window, between 0
+	// and 1, at which the breaker opens.
+	FailureRatio float64
+	// MinRequests is the minimum number of attempts in the window before the
+	// breaker may open.
+	MinRequests int
+	// Window is the duration over which attempts are counted.
+	Window time.Duration
+	// OpenTimeout is how long the breaker stays open before letting probe
+	// attempts through.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probe attempts that must succeed for
+	// the breaker to close.
+	HalfOpenRequests int
+	// IsFailure reports whether an error counts as a failure. Defaults to
+	// IsTransient, so errors such as invalid parameters don't count, as the
+	// API is still up.
+	IsFailure func(err error) bool
+	// OnStateChange, if set, is called when the state of the breaker changes.
+	OnStateChange func(from, to BreakerState)
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+	changes     [][2]BreakerState
+	now         func() time.Time
+}
+
+// NewCircuitBreaker returns a new closed CircuitBreaker with the default
+// settings.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureRatio:     DefaultBreakerFailureRatio,
+		MinRequests:      DefaultBreakerMinRequests,
+		Window:           DefaultBreakerWindow,
+		OpenTimeout:      DefaultBreakerOpenTimeout,
+		HalfOpenRequests: DefaultBreakerHalfOpenRequests,
+	}
+}
+
+// SetBreaker sets the circuit breaker requests made by the API client go
+// through. Pass nil to disable it.
+func (a *API) SetBreaker(breaker *CircuitBreaker) {
+	a.Breaker = breaker
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.advance()
+	return b.state
+}
+
+// Allow returns a *CircuitOpenError if a request attempt may not be made.
+// Otherwise, the result of the attempt must be reported with Record.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	return b.allow()
+}
+
+// Record records the result of an attempt allowed by Allow. Context errors
+// aren't counted.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.record(err)
+}
+
+// release releases an attempt allowed by Allow that wasn't made. It's a no-op
+// if b is nil.
+func (b *CircuitBreaker) release() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.advance()
+
+	switch b.state {
+	case BreakerOpen:
+		return &CircuitOpenError{Until: b.openedAt.Add(b.openTimeout())}
+	case BreakerHalfOpen:
+		if b.probes >= b.halfOpenRequests() {
+			return &CircuitOpenError{Until: b.time()}
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *CircuitBreaker) record(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if b.state == BreakerHalfOpen && b.probes > 0 {
+			b.probes--
+		}
+		return
+	}
+
+	failed := err != nil && b.isFailure(err)
+
+	switch b.state {
+	case BreakerClosed:
+		b.advance()
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.minRequests() &&
+			float64(b.failures) >= b.failureRatio()*float64(b.requests) {
+			b.open()
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests() {
+			b.close()
+		}
+	}
+}
+
+// advance resets the window once it has passed, and moves an open breaker to
+// half-open once its open timeout has passed.
+func (b *CircuitBreaker) advance() {
+	now := b.time()
+
+	switch b.state {
+	case BreakerClosed:
+		if now.Sub(b.windowStart) >= b.window() {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.openTimeout() {
+			b.setState(BreakerHalfOpen)
+			b.probes, b.successes = 0, 0
+		}
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.setState(BreakerOpen)
+	b.openedAt = b.time()
+}
+
+func (b *CircuitBreaker) close() {
+	b.setState(BreakerClosed)
+	b.windowStart, b.requests, b.failures = b.time(), 0, 0
+}
+
+// setState changes the state of the breaker, and queues the change to be
+// reported to OnStateChange once the lock is released.
+func (b *CircuitBreaker) setState(to BreakerState) {
+	if b.state != to {
+		b.changes = append(b.changes, [2]BreakerState{b.state, to})
+		b.state = to
+	}
+}
+
+// unlock releases the lock, and reports the queued state changes to
+// OnStateChange.
+func (b *CircuitBreaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	if b.OnStateChange == nil {
+		return
+	}
+	for _, c := range changes {
+		b.OnStateChange(c[0], c[1])
+	}
+}
+
+func (b *CircuitBreaker) failureRatio() float64 {
+	if b.FailureRatio <= 0 {
+		return DefaultBreakerFailureRatio
+	}
+	return b.FailureRatio
+}
+
+func (b *CircuitBreaker) minRequests() int {
+	if b.MinRequests <= 0 {
+		return DefaultBreakerMinRequests
+	}
+	return b.MinRequests
+}
+
+func (b *CircuitBreaker) window() time.Duration {
+	if b.Window <= 0 {
+		return DefaultBreakerWindow
+	}
+	return b.Window
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout <= 0 {
+		return DefaultBreakerOpenTimeout
+	}
+	return b.OpenTimeout
+}
+
+func (b *CircuitBreaker) halfOpenRequests() int {
+	if b.HalfOpenRequests <= 0 {
+		return DefaultBreakerHalfOpenRequests
+	}
+	return b.HalfOpenRequests
+}
+
+func (b *CircuitBreaker) isFailure(err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(err)
+	}
+	return IsTransient(err)
+}
+
+func (b *CircuitBreaker) time() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
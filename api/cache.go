@@ -0,0 +1,393 @@
+package api
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the default maximum number of responses kept by a
+// MemoryCacheStore.
+const DefaultCacheSize = 1000
+
+// DefaultCacheTTLs are the default times responses of API methods are cached
+// for. Methods that aren't listed aren't cached.
+var DefaultCacheTTLs = map[APIMethod]time.Duration{
+	AlbumGetInfoMethod:        24 * time.Hour,
+	AlbumGetTopTagsMethod:     24 * time.Hour,
+	ArtistGetCorrectionMethod: 7 * 24 * time.Hour,
+	ArtistGetInfoMethod:       24 * time.Hour,
+	ArtistGetSimilarMethod:    24 * time.Hour,
+	ArtistGetTopTagsMethod:    24 * time.Hour,
+	TagGetInfoMethod:          24 * time.Hour,
+	TagGetSimilarMethod:       24 * time.Hour,
+	TrackGetCorrectionMethod:  7 * 24 * time.Hour,
+	TrackGetInfoMethod:        24 * time.Hour,
+	TrackGetSimilarMethod:     24 * time.Hour,
+	TrackGetTopTagsMethod:     24 * time.Hour,
+}
+
+// userDataObjects are the packages of API methods whose responses depend on
+// the data of a user, and which any write may change.
+var userDataObjects = []string{"user", "library"}
+
+// entityParams are the parameters that identify the artist, album or track
+// affected by a write.
+var entityParams = []string{"artist", "album", "track"}
+
+// CacheStore stores cached API responses. Implementations must be safe for
+// concurrent use.
+type CacheStore interface {
+	// Get returns the value stored for the given key, if it exists and hasn't
+	// expired.
+	Get(key string) ([]byte, bool)
+	// Set stores the value for the given key, for the given duration.
+	Set(key string, value []byte, ttl time.Duration)
+	// DeleteFunc deletes the values of all keys for which del returns true.
+	DeleteFunc(del func(key string) bool)
+}
+
+// Cache caches the responses of unsigned GET requests to the API, for a time
+// set per API method. Requests signed with the API secret or a session key are
+// never cached. Successful POST requests, such as track.love, invalidate the
+// cached responses for the same artist, album or track, and all cached
+// responses of user and library methods.
+//
+// Responses are cached by API endpoint and query, excluding the API key, so a
+// CacheStore may be shared between API clients.
+type Cache struct {
+	// Store is where cached responses are stored.
+	Store CacheStore
+	// TTLs are the times responses of API methods are cached for. Methods
+	// that aren't listed aren't cached.
+	TTLs map[APIMethod]time.Duration
+}
+
+// NewCache returns a new Cache that stores responses in the given store, with
+// a copy of DefaultCacheTTLs.
+func NewCache(store CacheStore) *Cache {
+	return &Cache{Store: store, TTLs: maps.Clone(DefaultCacheTTLs)}
+}
+
+// NewMemoryCache returns a new Cache that stores up to DefaultCacheSize
+// responses in memory.
+func NewMemoryCache() *Cache {
+	return NewCache(NewMemoryCacheStore(DefaultCacheSize))
+}
+
+// TTL returns the time responses of the given API method are cached for, or 0
+// if they aren't cached.
+func (c *Cache) TTL(method APIMethod) time.Duration {
+	return c.TTLs[method]
+}
+
+// Invalidate deletes the cached responses affected by a write with the given
+// parameters. Auth methods don't invalidate any responses. Batched writes,
+// such as track.scrobble, invalidate the responses of each of their entities,
+// given by indexed parameters such as artist[0] and track[0].
+func (c *Cache) Invalidate(params url.Values) {
+	object, _, _ := strings.Cut(params.Get("method"), ".")
+	if object == "auth" {
+		return
+	}
+
+	entities := writeEntities(params)
+
+	c.Store.DeleteFunc(func(key string) bool {
+		_, query, _ := strings.Cut(key, "?")
+		p, err := url.ParseQuery(query)
+		if err != nil {
+			return true
+		}
+
+		obj, _, _ := strings.Cut(p.Get("method"), ".")
+		for _, o := range userDataObjects {
+			if obj == o {
+				return true
+			}
+		}
+		if obj != object {
+			return false
+		}
+
+		for _, e := range entities {
+			if matchesEntity(e, p) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// writeEntities returns the entity parameters of each artist, album or track
+// affected by a write. Batched writes have an entity for each index of their
+// indexed parameters.
+func writeEntities(params url.Values) []url.Values {
+	var entities []url.Values
+	for i := 0; ; i++ {
+		e := make(url.Values)
+		for _, name := range entityParams {
+			if v := params.Get(name + "[" + strconv.Itoa(i) + "]"); v != "" {
+				e.Set(name, v)
+			}
+		}
+		if len(e) == 0 {
+			break
+		}
+		entities = append(entities, e)
+	}
+
+	if len(entities) == 0 {
+		entities = append(entities, params)
+	}
+
+	return entities
+}
+
+// matchesEntity reports whether the cached response with the given
+// parameters may be affected by a write to the given entity.
+func matchesEntity(entity, cached url.Values) bool {
+	for _, name := range entityParams {
+		v, c := entity.Get(name), cached.Get(name)
+		if v != "" && c != "" && !strings.EqualFold(v, c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// invalidateBody deletes the cached responses affected by a write with the
+// given request body.
+func (c *Cache) invalidateBody(body string) {
+	if p, err := url.ParseQuery(body); err == nil {
+		c.Invalidate(p)
+	}
+}
+
+// key returns the cache key and time to live of a request, or a zero time to
+// live if the request can't be cached.
+func (c *Cache) key(method, rawURL string) (string, time.Duration) {
+	if !isUnsignedGet(method, rawURL) {
+		return "", 0
+	}
+
+	endpoint, query, _ := strings.Cut(rawURL, "?")
+	p, _ := url.ParseQuery(query)
+
+	ttl := c.TTL(APIMethod(p.Get("method")))
+	if ttl <= 0 {
+		return "", 0
+	}
+
+	p.Del("api_key")
+	return endpoint + "?" + p.Encode(), ttl
+}
+
+// MemoryCacheStore is a CacheStore that keeps values in memory, evicting the
+// least recently used values once it's full.
+type MemoryCacheStore struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+}
+
+type memoryCacheEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCacheStore returns a new MemoryCacheStore that holds up to size
+// values.
+func NewMemoryCacheStore(size int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		size:    max(size, 1),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Len returns the number of values in the store, including expired values
+// that haven't been evicted yet.
+func (s *MemoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+// Get returns the value stored for the given key, if it exists and hasn't
+// expired.
+func (s *MemoryCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*memoryCacheEntry)
+	if !s.now().Before(e.expires) {
+		s.remove(el)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(el)
+	return e.value, true
+}
+
+// Set stores the value for the given key, for the given duration, evicting
+// the least recently used value if the store is full.
+func (s *MemoryCacheStore) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &memoryCacheEntry{key: key, value: value, expires: s.now().Add(ttl)}
+
+	if el, ok := s.entries[key]; ok {
+		el.Value = e
+		s.lru.MoveToFront(el)
+		return
+	}
+
+	s.entries[key] = s.lru.PushFront(e)
+	for s.lru.Len() > s.size {
+		s.remove(s.lru.Back())
+	}
+}
+
+// DeleteFunc deletes the values of all keys for which del returns true.
+func (s *MemoryCacheStore) DeleteFunc(del func(key string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, el := range s.entries {
+		if del(key) {
+			s.remove(el)
+		}
+	}
+}
+
+func (s *MemoryCacheStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryCacheEntry).key)
+}
+
+// DiskCacheStore is a CacheStore that keeps values in files in a directory,
+// so they persist between runs. Expired values are deleted when they're read.
+type DiskCacheStore struct {
+	mu  sync.Mutex
+	dir string
+	now func() time.Time
+}
+
+type diskCacheEntry struct {
+	Key     string    `json:"key"`
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewDiskCacheStore returns a new DiskCacheStore that keeps values in the
+// given directory, creating it if it doesn't exist.
+func NewDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &DiskCacheStore{dir: dir, now: time.Now}, nil
+}
+
+// Get returns the value stored for the given key, if it exists and hasn't
+// expired.
+func (s *DiskCacheStore) Get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+
+	e, err := readDiskCacheEntry(path)
+	if err != nil || e.Key != key {
+		return nil, false
+	}
+	if !s.now().Before(e.Expires) {
+		os.Remove(path)
+		return nil, false
+	}
+
+	return e.Value, true
+}
+
+// Set stores the value for the given key, for the given duration. Errors
+// writing the value are ignored, as the value can be fetched again.
+func (s *DiskCacheStore) Set(key string, value []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(diskCacheEntry{
+		Key:     key,
+		Value:   value,
+		Expires: s.now().Add(ttl),
+	})
+	if err != nil {
+		return
+	}
+
+	path := s.path(key)
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// DeleteFunc deletes the values of all keys for which del returns true.
+func (s *DiskCacheStore) DeleteFunc(del func(key string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return
+	}
+
+	for _, path := range files {
+		e, err := readDiskCacheEntry(path)
+		if err != nil || del(e.Key) {
+			os.Remove(path)
+		}
+	}
+}
+
+func (s *DiskCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func readDiskCacheEntry(path string) (diskCacheEntry, error) {
+	var e diskCacheEntry
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return e, err
+	}
+
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
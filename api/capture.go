@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ResponseInfo holds the raw response to a call to the API, for debugging.
+type ResponseInfo struct {
+	// URL is the URL of the final request, with credentials redacted. The
+	// parameters of POST requests are in the body, and aren't included.
+	URL string
+	// StatusCode is the HTTP status code of the response, or 0 if the
+	// response was cached.
+	StatusCode int
+	// Header is the header of the response, including rate limit headers, or
+	// nil if the response was cached.
+	Header http.Header
+	// Body is the raw body of the response.
+	Body []byte
+	// Attempts is the number of attempts made, or 0 if the response was
+	// cached.
+	Attempts uint
+	// Cached is true if the response was served from the cache.
+	Cached bool
+}
+
+type responseInfoKey struct{}
+
+// WithResponse returns a copy of the context that makes calls to the API using
+// it store the raw response to the call in info, whether the call succeeds or
+// fails. If the call fails, the response is also attached to the returned
+// *LastFMError or *HTTPError.
+//
+// Use it with the Context variants of methods, e.g.
+//
+//	var info api.ResponseInfo
+//	ctx := api.WithResponse(context.Background(), &info)
+//	artist, err := client.Artist.InfoContext(ctx, params)
+//	fmt.Println(info.Header.Get("X-RateLimit-Remaining"))
+func WithResponse(ctx context.Context, info *ResponseInfo) context.Context {
+	return context.WithValue(ctx, responseInfoKey{}, info)
+}
+
+// responseInfo returns the ResponseInfo set by WithResponse, if any.
+func responseInfo(ctx context.Context) *ResponseInfo {
+	info, _ := ctx.Value(responseInfoKey{}).(*ResponseInfo)
+	return info
+}
+
+// SetCaptureResponses sets whether the raw responses of failed calls are
+// attached to the returned *LastFMError and *HTTPError.
+func (a *API) SetCaptureResponses(capture bool) {
+	a.CaptureResponses = capture
+}
+
+// newResponseInfo returns the ResponseInfo of an attempt at a request.
+func newResponseInfo(
+	req *http.Request, res *http.Response, raw []byte, attempt uint) *ResponseInfo {
+
+	if res == nil {
+		return nil
+	}
+
+	return &ResponseInfo{
+		URL:        RedactURL(req.URL.String()),
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       raw,
+		Attempts:   attempt,
+	}
+}
+
+// attachResponse attaches the given response to the Last.fm and HTTP errors
+// err contains.
+func attachResponse(err error, info *ResponseInfo) {
+	if info == nil {
+		return
+	}
+
+	var lferr *LastFMError
+	if errors.As(err, &lferr) {
+		lferr.Response = info
+	}
+
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		herr.Response = info
+	}
+}
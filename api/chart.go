@@ -1,6 +1,11 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+	"iter"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Chart struct {
 	api *API
@@ -13,35 +18,164 @@ func NewChart(api *API) *Chart {
 
 // TopArtistsLimit returns the top artists of the chart.
 func (c Chart) TopArtistsLimit(params *lastfm.ChartTopArtistsParams) (*lastfm.ChartTopArtists, error) {
+	return c.TopArtistsLimitContext(context.Background(), params)
+}
+
+// TopArtistsLimitContext is like TopArtistsLimit but uses the given context for
+// the request.
+func (c Chart) TopArtistsLimitContext(
+	ctx context.Context, params *lastfm.ChartTopArtistsParams) (*lastfm.ChartTopArtists, error) {
+
 	var res lastfm.ChartTopArtists
-	return &res, c.api.Get(&res, ChartGetTopArtistsMethod, params)
+	return &res, c.api.GetContext(ctx, &res, ChartGetTopArtistsMethod, params)
 }
 
 // TopArtists returns all the top artists of the chart. Same as
 // TopArtistsLimit(nil).
 func (c Chart) TopArtists() (*lastfm.ChartTopArtists, error) {
-	return c.TopArtistsLimit(nil)
+	return c.TopArtistsContext(context.Background())
+}
+
+// TopArtistsContext is like TopArtists but uses the given context for the
+// request.
+func (c Chart) TopArtistsContext(ctx context.Context) (*lastfm.ChartTopArtists, error) {
+	return c.TopArtistsLimitContext(ctx, nil)
+}
+
+// TopArtistsAll returns an iterator over the top artists of the chart on every
+// page, starting from params.Page, or the first page if params is nil.
+func (c Chart) TopArtistsAll(
+	params *lastfm.ChartTopArtistsParams) iter.Seq2[lastfm.ChartTopArtist, error] {
+
+	return c.TopArtistsAllContext(context.Background(), params)
+}
+
+// TopArtistsAllContext is like TopArtistsAll but uses the given context for the
+// requests.
+func (c Chart) TopArtistsAllContext(
+	ctx context.Context, params *lastfm.ChartTopArtistsParams) iter.Seq2[lastfm.ChartTopArtist, error] {
+
+	var p lastfm.ChartTopArtistsParams
+	if params != nil {
+		p = *params
+	}
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.ChartTopArtists, error) {
+		p := p
+		p.Page = page
+		return c.TopArtistsLimitContext(ctx, &p)
+	}
+	items := func(res *lastfm.ChartTopArtists) ([]lastfm.ChartTopArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, p.Page, fetch, items)
 }
 
 // TopTagsLimit returns the top tags of the chart.
 func (c Chart) TopTagsLimit(params *lastfm.ChartTopTagsParams) (*lastfm.ChartTopTags, error) {
+	return c.TopTagsLimitContext(context.Background(), params)
+}
+
+// TopTagsLimitContext is like TopTagsLimit but uses the given context for the
+// request.
+func (c Chart) TopTagsLimitContext(
+	ctx context.Context, params *lastfm.ChartTopTagsParams) (*lastfm.ChartTopTags, error) {
+
 	var res lastfm.ChartTopTags
-	return &res, c.api.Get(&res, ChartGetTopTagsMethod, params)
+	return &res, c.api.GetContext(ctx, &res, ChartGetTopTagsMethod, params)
 }
 
 // TopTags returns the top tags of the chart. Same as TopTagsLimit(nil).
 func (c Chart) TopTags() (*lastfm.ChartTopTags, error) {
-	return c.TopTagsLimit(nil)
+	return c.TopTagsContext(context.Background())
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (c Chart) TopTagsContext(ctx context.Context) (*lastfm.ChartTopTags, error) {
+	return c.TopTagsLimitContext(ctx, nil)
+}
+
+// TopTagsAll returns an iterator over the top tags of the chart on every page,
+// starting from params.Page, or the first page if params is nil.
+func (c Chart) TopTagsAll(params *lastfm.ChartTopTagsParams) iter.Seq2[lastfm.ChartTopTag, error] {
+	return c.TopTagsAllContext(context.Background(), params)
+}
+
+// TopTagsAllContext is like TopTagsAll but uses the given context for the
+// requests.
+func (c Chart) TopTagsAllContext(
+	ctx context.Context, params *lastfm.ChartTopTagsParams) iter.Seq2[lastfm.ChartTopTag, error] {
+
+	var p lastfm.ChartTopTagsParams
+	if params != nil {
+		p = *params
+	}
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.ChartTopTags, error) {
+		p := p
+		p.Page = page
+		return c.TopTagsLimitContext(ctx, &p)
+	}
+	items := func(res *lastfm.ChartTopTags) ([]lastfm.ChartTopTag, int, int) {
+		return res.Tags, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, p.Page, fetch, items)
 }
 
 // TopTracksLimit returns the top tracks of the chart.
 func (c Chart) TopTracksLimit(params *lastfm.ChartTopTracksParams) (*lastfm.ChartTopTracks, error) {
+	return c.TopTracksLimitContext(context.Background(), params)
+}
+
+// TopTracksLimitContext is like TopTracksLimit but uses the given context for
+// the request.
+func (c Chart) TopTracksLimitContext(
+	ctx context.Context, params *lastfm.ChartTopTracksParams) (*lastfm.ChartTopTracks, error) {
+
 	var res lastfm.ChartTopTracks
-	return &res, c.api.Get(&res, ChartGetTopTracksMethod, params)
+	return &res, c.api.GetContext(ctx, &res, ChartGetTopTracksMethod, params)
 }
 
 // TopTracks returns all the top tracks of the chart. Same as
 // TopTracksLimit(nil).
 func (c Chart) TopTracks() (*lastfm.ChartTopTracks, error) {
-	return c.TopTracksLimit(nil)
+	return c.TopTracksContext(context.Background())
+}
+
+// TopTracksContext is like TopTracks but uses the given context for the
+// request.
+func (c Chart) TopTracksContext(ctx context.Context) (*lastfm.ChartTopTracks, error) {
+	return c.TopTracksLimitContext(ctx, nil)
+}
+
+// TopTracksAll returns an iterator over the top tracks of the chart on every
+// page, starting from params.Page, or the first page if params is nil.
+func (c Chart) TopTracksAll(
+	params *lastfm.ChartTopTracksParams) iter.Seq2[lastfm.ChartTopTrack, error] {
+
+	return c.TopTracksAllContext(context.Background(), params)
+}
+
+// TopTracksAllContext is like TopTracksAll but uses the given context for the
+// requests.
+func (c Chart) TopTracksAllContext(
+	ctx context.Context, params *lastfm.ChartTopTracksParams) iter.Seq2[lastfm.ChartTopTrack, error] {
+
+	var p lastfm.ChartTopTracksParams
+	if params != nil {
+		p = *params
+	}
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.ChartTopTracks, error) {
+		p := p
+		p.Page = page
+		return c.TopTracksLimitContext(ctx, &p)
+	}
+	items := func(res *lastfm.ChartTopTracks) ([]lastfm.ChartTopTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, p.Page, fetch, items)
 }
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// flightGroup coalesces identical requests that are in flight at the same
+// time into a single request.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// sendFunc sends a request and returns its result.
+type sendFunc func(ctx context.Context) (sendResult, error)
+
+// flight is a request in flight, shared by one or more callers.
+type flight struct {
+	done    chan struct{}
+	waiters int
+	cancel  context.CancelFunc
+
+	res sendResult
+	err error
+}
+
+// do calls fn and returns its results, unless a call for the same key is
+// already in flight, in which case it waits for that call and returns its
+// results instead, and shared is true.
+//
+// The call isn't cancelled when the context of the caller that started it is
+// cancelled, but only once every caller waiting for it has given up.
+func (g *flightGroup) do(
+	ctx context.Context, key string, fn sendFunc) (res sendResult, shared bool, err error) {
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+
+	f, ok := g.calls[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = f
+
+		go func() {
+			f.res, f.err = fn(fctx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, f)
+			g.mu.Unlock()
+
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.res, ok, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+
+		return sendResult{}, ok, ctx.Err()
+	}
+}
+
+// forget removes the given call from the group, if it's still the call in
+// flight for the key, so later callers start a new call.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+}
+
+// isUnsignedGet reports whether a request is a GET request that isn't signed
+// with the API secret or a session key.
+func isUnsignedGet(method, rawURL string) bool {
+	if method != http.MethodGet {
+		return false
+	}
+
+	_, query, _ := strings.Cut(rawURL, "?")
+	p, err := url.ParseQuery(query)
+
+	return err == nil && !p.Has("api_sig") && !p.Has("sk")
+}
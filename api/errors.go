@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Sentinel errors for each ErrorCode, to be used with errors.Is. An error
+// matches a sentinel if it's a *LastFMError with the same code, regardless of
+// its message, e.g.
+//
+//	if errors.Is(err, api.ErrInvalidSessionKeyError) {
+//		// Re-authenticate the user.
+//	}
+var (
+	ErrInvalidServiceError         = NewLastFMError(ErrInvalidService, "Invalid service")
+	ErrInvalidMethodError          = NewLastFMError(ErrInvalidMethod, "Invalid method")
+	ErrAuthenticationFailedError   = NewLastFMError(ErrAuthenticationFailed, "Authentication failed")
+	ErrInvalidFormatError          = NewLastFMError(ErrInvalidFormat, "Invalid format")
+	ErrInvalidParametersError      = NewLastFMError(ErrInvalidParameters, "Invalid parameters")
+	ErrInvalidResourceError        = NewLastFMError(ErrInvalidResource, "Invalid resource specified")
+	ErrOperationFailedError        = NewLastFMError(ErrOperationFailed, "Operation failed")
+	ErrInvalidSessionKeyError      = NewLastFMError(ErrInvalidSessionKey, "Invalid session key")
+	ErrInvalidAPIKeyError          = NewLastFMError(ErrInvalidAPIKey, "Invalid API key")
+	ErrServiceOfflineError         = NewLastFMError(ErrServiceOffline, "Service offline")
+	ErrSubscribersOnlyError        = NewLastFMError(ErrSubscribersOnly, "Subscribers only")
+	ErrInvalidMethodSignatureError = NewLastFMError(ErrInvalidMethodSignature, "Invalid method signature")
+	ErrUnauthorizedTokenError      = NewLastFMError(ErrUnauthorizedToken, "Unauthorized token")
+	ErrItemNotStreamableError      = NewLastFMError(ErrItemNotStreamable, "Item not streamable")
+	ErrServiceUnavailableError     = NewLastFMError(ErrServiceUnavailable, "Service temporarily unavailable")
+	ErrUserNotLoggedInError        = NewLastFMError(ErrUserNotLoggedIn, "User not logged in")
+	ErrTrialExpiredError           = NewLastFMError(ErrTrialExpired, "Trial expired")
+	ErrNotEnoughContentError       = NewLastFMError(ErrNotEnoughContent, "Not enough content")
+	ErrNotEnoughMembersError       = NewLastFMError(ErrNotEnoughMembers, "Not enough members")
+	ErrNotEnoughFansError          = NewLastFMError(ErrNotEnoughFans, "Not enough fans")
+	ErrNotEnoughNeighboursError    = NewLastFMError(ErrNotEnoughNeighbours, "Not enough neighbours")
+	ErrNoPeakRadioError            = NewLastFMError(ErrNoPeakRadio, "No peak radio")
+	ErrRadioNotFoundError          = NewLastFMError(ErrRadioNotFound, "Radio not found")
+	ErrAPIKeySuspendedError        = NewLastFMError(ErrAPIKeySuspended, "API key suspended")
+	ErrDeprecatedError             = NewLastFMError(ErrDeprecated, "Deprecated")
+	ErrRateLimitExceededError      = NewLastFMError(ErrRateLimitExceeded, "Rate limit exceeded")
+
+	ErrAPIKeyMissingError   = NewLastFMError(ErrAPIKeyMissing, APIKeyMissingMessage)
+	ErrSecretRequiredError  = NewLastFMError(ErrSecretRequired, SecretRequiredMessage)
+	ErrSessionRequiredError = NewLastFMError(ErrSessionRequired, SessionRequiredMessage)
+)
+
+// authErrorCodes are the error codes caused by invalid or missing credentials.
+var authErrorCodes = []ErrorCode{
+	ErrAuthenticationFailed,
+	ErrInvalidSessionKey,
+	ErrInvalidAPIKey,
+	ErrInvalidMethodSignature,
+	ErrUnauthorizedToken,
+	ErrUserNotLoggedIn,
+	ErrAPIKeySuspended,
+	ErrAPIKeyMissing,
+	ErrSecretRequired,
+	ErrSessionRequired,
+}
+
+// IsAuthError reports whether err is a Last.fm error caused by invalid or
+// missing credentials, such as an invalid API key, signature, token or session
+// key. Such requests won't succeed until the credentials are fixed, or the
+// user is re-authenticated.
+func IsAuthError(err error) bool {
+	var lferr *LastFMError
+	if !errors.As(err, &lferr) {
+		return false
+	}
+
+	for _, code := range authErrorCodes {
+		if lferr.IsCode(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTransient reports whether err is likely to be resolved by retrying the
+// request later. This is the case for Last.fm errors for which
+// LastFMError.ShouldRetry returns true, ErrServiceOffline, HTTP 429 and 5xx
+// errors without a Last.fm error, and transient network errors.
+func IsTransient(err error) bool {
+	var lferr *LastFMError
+	if errors.As(err, &lferr) {
+		return lferr.ShouldRetry() || lferr.IsCode(ErrServiceOffline)
+	}
+
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.StatusCode == http.StatusTooManyRequests || herr.StatusCode >= 500
+	}
+
+	return IsTransientNetworkError(err)
+}
+
+// IsPermanent reports whether err is a Last.fm or HTTP error that will occur
+// again if the same request is retried, such as invalid parameters or
+// credentials. Context errors are neither transient nor permanent.
+func IsPermanent(err error) bool {
+	if err == nil || IsTransient(err) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var lferr *LastFMError
+	var herr *HTTPError
+	return errors.As(err, &lferr) || errors.As(err, &herr)
+}
+
+// annotateError sets the API method and number of attempts of the request that
+// caused err on the Last.fm and HTTP errors it contains.
+func annotateError(err error, method APIMethod, attempts uint) {
+	var lferr *LastFMError
+	if errors.As(err, &lferr) {
+		lferr.Method, lferr.Attempts = method, attempts
+	}
+
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		herr.Method, herr.Attempts = method, attempts
+	}
+}
@@ -1,6 +1,11 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+	"iter"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Geo struct {
 	api *API
@@ -13,12 +18,76 @@ func NewGeo(api *API) *Geo {
 
 // TopArtists returns the top artists of a country.
 func (g Geo) TopArtists(params lastfm.GeoTopArtistsParams) (*lastfm.GeoTopArtists, error) {
+	return g.TopArtistsContext(context.Background(), params)
+}
+
+// TopArtistsContext is like TopArtists but uses the given context for the
+// request.
+func (g Geo) TopArtistsContext(
+	ctx context.Context, params lastfm.GeoTopArtistsParams) (*lastfm.GeoTopArtists, error) {
+
 	var res lastfm.GeoTopArtists
-	return &res, g.api.Get(&res, GeoGetTopArtistsMethod, params)
+	return &res, g.api.GetContext(ctx, &res, GeoGetTopArtistsMethod, params)
+}
+
+// TopArtistsAll returns an iterator over the top artists of a country on every
+// page, starting from params.Page.
+func (g Geo) TopArtistsAll(
+	params lastfm.GeoTopArtistsParams) iter.Seq2[lastfm.GeoTopArtist, error] {
+
+	return g.TopArtistsAllContext(context.Background(), params)
+}
+
+// TopArtistsAllContext is like TopArtistsAll but uses the given context for the
+// requests.
+func (g Geo) TopArtistsAllContext(
+	ctx context.Context, params lastfm.GeoTopArtistsParams) iter.Seq2[lastfm.GeoTopArtist, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.GeoTopArtists, error) {
+		p := params
+		p.Page = page
+		return g.TopArtistsContext(ctx, p)
+	}
+	items := func(res *lastfm.GeoTopArtists) ([]lastfm.GeoTopArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopTracks returns the top tracks of a country.
 func (g Geo) TopTracks(params lastfm.GeoTopTracksParams) (*lastfm.GeoTopTracks, error) {
+	return g.TopTracksContext(context.Background(), params)
+}
+
+// TopTracksContext is like TopTracks but uses the given context for the
+// request.
+func (g Geo) TopTracksContext(
+	ctx context.Context, params lastfm.GeoTopTracksParams) (*lastfm.GeoTopTracks, error) {
+
 	var res lastfm.GeoTopTracks
-	return &res, g.api.Get(&res, GeoGetTopTracksMethod, params)
+	return &res, g.api.GetContext(ctx, &res, GeoGetTopTracksMethod, params)
+}
+
+// TopTracksAll returns an iterator over the top tracks of a country on every
+// page, starting from params.Page.
+func (g Geo) TopTracksAll(params lastfm.GeoTopTracksParams) iter.Seq2[lastfm.GeoTopTrack, error] {
+	return g.TopTracksAllContext(context.Background(), params)
+}
+
+// TopTracksAllContext is like TopTracksAll but uses the given context for the
+// requests.
+func (g Geo) TopTracksAllContext(
+	ctx context.Context, params lastfm.GeoTopTracksParams) iter.Seq2[lastfm.GeoTopTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.GeoTopTracks, error) {
+		p := params
+		p.Page = page
+		return g.TopTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.GeoTopTracks) ([]lastfm.GeoTopTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
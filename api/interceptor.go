@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Call is a call to an API method, as seen by interceptors.
+type Call struct {
+	// Method is the API method called.
+	Method APIMethod
+	// HTTPMethod is the HTTP method of the request, e.g. "GET" or "POST".
+	HTTPMethod string
+	// Endpoint is the URL the request is sent to, without the query.
+	Endpoint string
+	// Params are the parameters of the request, including the API key, and
+	// the session key and signature of signed requests. Interceptors may
+	// modify them, but must re-sign signed calls if they do.
+	Params url.Values
+	// StatusCode is the HTTP status code of the last attempt at the call, or 0
+	// if no response was received or the response was cached. It's set once
+	// the call has been made.
+	StatusCode int
+	// Attempts is the number of attempts made at the call, or 0 if the
+	// response was cached or the call was coalesced. It's set once the call
+	// has been made.
+	Attempts uint
+	// Coalesced is true if the call shared the request of an identical call
+	// in flight at the same time, instead of sending its own. It's set once
+	// the call has been made.
+	Coalesced bool
+}
+
+// Invoker performs a call to the API and returns the response.
+type Invoker func(ctx context.Context, call *Call) (LFMWrapper, error)
+
+// Interceptor intercepts calls to the API. It may inspect or modify the call
+// before passing it on to next, and inspect or replace the response and error
+// returned by next. It may also return a response or error without calling
+// next at all.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (LFMWrapper, error)
+
+// Use appends the given interceptors to the interceptor chain of the API
+// client. Interceptors are called in the order they were added, with the first
+// interceptor seeing each call first and each response last.
+func (a *API) Use(interceptors ...Interceptor) {
+	a.Interceptors = append(a.Interceptors, interceptors...)
+}
+
+// invoker returns the invoker that calls the interceptor chain of the API
+// client, ending in invoke.
+func (a *API) invoker() Invoker {
+	next := a.invoke
+	for i := len(a.Interceptors) - 1; i >= 0; i-- {
+		next = intercept(a.Interceptors[i], next)
+	}
+	return next
+}
+
+func intercept(interceptor Interceptor, next Invoker) Invoker {
+	return func(ctx context.Context, call *Call) (LFMWrapper, error) {
+		return interceptor(ctx, call, next)
+	}
+}
+
+// newCall returns the call for a request to the given URL with the given body.
+func newCall(method, rawURL, body string) (*Call, error) {
+	call := &Call{HTTPMethod: method, Endpoint: rawURL}
+
+	query := body
+	if method == http.MethodGet {
+		call.Endpoint, query, _ = strings.Cut(rawURL, "?")
+	}
+
+	p, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	call.Params = p
+	call.Method = APIMethod(p.Get("method"))
+
+	return call, nil
+}
+
+// request returns the URL and body of the request for the call.
+func (c *Call) request() (rawURL, body string) {
+	if c.HTTPMethod != http.MethodGet {
+		return c.Endpoint, c.Params.Encode()
+	}
+	if len(c.Params) == 0 {
+		return c.Endpoint, ""
+	}
+	return c.Endpoint + "?" + c.Params.Encode(), ""
+}
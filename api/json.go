@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// Format is the format the API is asked to respond in.
+type Format string
+
+const (
+	// FormatXML requests responses in XML. This is the default format.
+	FormatXML Format = "xml"
+	// FormatJSON requests responses in JSON, which is smaller on the wire.
+	// JSON responses are decoded into the same types as XML responses.
+	FormatJSON Format = "json"
+)
+
+// JSON keys Last.fm uses to represent XML attributes and character data.
+const (
+	jsonAttrKey = "@attr"
+	jsonTextKey = "#text"
+)
+
+// setFormat adds the format parameter for the given format to the URL query of
+// a GET request, or to the body of a POST request.
+func setFormat(format Format, httpMethod, rawURL, body string) (string, string, error) {
+	if format == "" || format == FormatXML {
+		return rawURL, body, nil
+	}
+
+	if httpMethod == http.MethodPost {
+		p, err := url.ParseQuery(body)
+		if err != nil {
+			return "", "", err
+		}
+		p.Set("format", string(format))
+		return rawURL, p.Encode(), nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	p := u.Query()
+	p.Set("format", string(format))
+	u.RawQuery = p.Encode()
+
+	return u.String(), body, nil
+}
+
+// decodeJSON decodes a Last.fm JSON response into the given LFMWrapper by
+// converting it into the equivalent XML response, so it can be unmarshaled
+// into the same types as XML responses.
+//
+// Last.fm's JSON responses are generated from its XML responses, so the
+// conversion reverses it: members of "@attr" objects become attributes, and
+// objects with a "#text" member become elements with that character data and
+// their other scalar members as attributes. Arrays become repeated elements,
+// so single elements not wrapped in an array are handled the same way. Error
+// responses become a failed LFMWrapper.
+func decodeJSON(r io.Reader, lfm *LFMWrapper) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var root map[string]any
+	if err := dec.Decode(&root); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+
+	if code, ok := root["error"]; ok {
+		lfm.Status = "failed"
+		start := xml.StartElement{
+			Name: xml.Name{Local: "error"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "code"}, Value: jsonScalar(code)}},
+		}
+		if err := enc.EncodeElement(jsonScalar(root["message"]), start); err != nil {
+			return err
+		}
+	} else {
+		lfm.Status = "ok"
+		for _, k := range slices.Sorted(maps.Keys(root)) {
+			if err := encodeJSONElement(enc, k, root[k]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	lfm.XMLName = xml.Name{Local: "lfm"}
+	lfm.InnerXML = buf.Bytes()
+
+	return nil
+}
+
+// encodeJSONElement encodes the JSON value v as one or more XML elements with
+// the given name.
+func encodeJSONElement(enc *xml.Encoder, name string, v any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch v := v.(type) {
+	case []any:
+		for _, item := range v {
+			if err := encodeJSONElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		return encodeJSONObject(enc, start, v)
+	default:
+		return enc.EncodeElement(jsonScalar(v), start)
+	}
+}
+
+func encodeJSONObject(enc *xml.Encoder, start xml.StartElement, obj map[string]any) error {
+	text, hasText := obj[jsonTextKey]
+
+	if attrs, ok := obj[jsonAttrKey].(map[string]any); ok {
+		for _, k := range slices.Sorted(maps.Keys(attrs)) {
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xml.Name{Local: k},
+				Value: jsonScalar(attrs[k]),
+			})
+		}
+	}
+
+	var children []string
+	for _, k := range slices.Sorted(maps.Keys(obj)) {
+		if k == jsonAttrKey || k == jsonTextKey {
+			continue
+		}
+		if hasText && isJSONScalar(obj[k]) {
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xml.Name{Local: k},
+				Value: jsonScalar(obj[k]),
+			})
+			continue
+		}
+		children = append(children, k)
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if hasText {
+		if err := enc.EncodeToken(xml.CharData(jsonScalar(text))); err != nil {
+			return err
+		}
+	}
+	for _, k := range children {
+		if err := encodeJSONElement(enc, k, obj[k]); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+func isJSONScalar(v any) bool {
+	switch v.(type) {
+	case []any, map[string]any:
+		return false
+	default:
+		return true
+	}
+}
+
+// jsonScalar returns the XML representation of a scalar JSON value. Booleans
+// are represented as Last.fm integer booleans.
+func jsonScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decodeResponse decodes the body of an API response in the given format into
+// the given LFMWrapper.
+func decodeResponse(format Format, r io.Reader, lfm *LFMWrapper) error {
+	if format != FormatJSON {
+		return xml.NewDecoder(r).Decode(lfm)
+	}
+
+	return decodeJSON(r, lfm)
+}
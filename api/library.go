@@ -1,6 +1,11 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+	"iter"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Library struct {
 	api *API
@@ -12,6 +17,38 @@ func NewLibrary(api *API) *Library {
 }
 
 func (l Library) Artists(params lastfm.LibraryArtistsParams) (*lastfm.LibraryArtists, error) {
+	return l.ArtistsContext(context.Background(), params)
+}
+
+// ArtistsContext is like Artists but uses the given context for the request.
+func (l Library) ArtistsContext(
+	ctx context.Context, params lastfm.LibraryArtistsParams) (*lastfm.LibraryArtists, error) {
+
 	var res lastfm.LibraryArtists
-	return &res, l.api.Get(&res, LibraryGetArtistsMethod, params)
+	return &res, l.api.GetContext(ctx, &res, LibraryGetArtistsMethod, params)
+}
+
+// ArtistsAll returns an iterator over the artists in a user's library on every
+// page, starting from params.Page.
+func (l Library) ArtistsAll(
+	params lastfm.LibraryArtistsParams) iter.Seq2[lastfm.LibraryArtist, error] {
+
+	return l.ArtistsAllContext(context.Background(), params)
+}
+
+// ArtistsAllContext is like ArtistsAll but uses the given context for the
+// requests.
+func (l Library) ArtistsAllContext(
+	ctx context.Context, params lastfm.LibraryArtistsParams) iter.Seq2[lastfm.LibraryArtist, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.LibraryArtists, error) {
+		p := params
+		p.Page = page
+		return l.ArtistsContext(ctx, p)
+	}
+	items := func(res *lastfm.LibraryArtists) ([]lastfm.LibraryArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
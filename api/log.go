@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the values of credentials in logs.
+const Redacted = "REDACTED"
+
+// RedactedParams are the parameters whose values are credentials, and are
+// redacted from logs.
+var RedactedParams = []string{"api_key", "sk", "api_sig", "password", "token"}
+
+// credentialPattern matches the session keys and auth tokens of auth
+// responses, in XML and JSON.
+var credentialPattern = regexp.MustCompile(
+	`<(key|token)>[^<]*</(?:key|token)>|"(key|token)":\s*"[^"]*"`)
+
+// RedactParams returns a copy of the given parameters with the values of
+// RedactedParams replaced with Redacted.
+func RedactParams(params url.Values) url.Values {
+	redacted := make(url.Values, len(params))
+	for k, v := range params {
+		if slices.Contains(RedactedParams, k) {
+			v = []string{Redacted}
+		}
+		redacted[k] = slices.Clone(v)
+	}
+	return redacted
+}
+
+// RedactURL returns the given URL with the values of RedactedParams in its
+// query replaced with Redacted.
+func RedactURL(rawURL string) string {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL
+	}
+
+	p, err := url.ParseQuery(query)
+	if err != nil {
+		return base + "?" + Redacted
+	}
+
+	return base + "?" + RedactParams(p).Encode()
+}
+
+// RedactBody returns the given response body with session keys and auth
+// tokens replaced with Redacted.
+func RedactBody(body string) string {
+	return credentialPattern.ReplaceAllStringFunc(body, func(m string) string {
+		sub := credentialPattern.FindStringSubmatch(m)
+		if sub[1] != "" {
+			return "<" + sub[1] + ">" + Redacted + "</" + sub[1] + ">"
+		}
+		return `"` + sub[2] + `":"` + Redacted + `"`
+	})
+}
+
+// SetLogger sets the logger used to log requests made by the API client. Pass
+// nil to disable logging.
+func (a *API) SetLogger(logger *slog.Logger) {
+	a.Logger = logger
+}
+
+// logAttempt logs an attempt at a request, which failed if at.Err is set.
+// Successful attempts are logged at the debug level, and failed attempts at
+// the warn level. The redacted request and response bodies are also logged at
+// the debug level.
+func (a *API) logAttempt(
+	ctx context.Context, req *http.Request, body string, at RetryAttempt, raw []byte) {
+
+	if a.Logger == nil {
+		return
+	}
+
+	query := body
+	if req.Method == http.MethodGet {
+		query = req.URL.RawQuery
+	}
+	params, _ := url.ParseQuery(query)
+
+	attrs := []slog.Attr{
+		slog.String("method", params.Get("method")),
+		slog.String("http_method", req.Method),
+		slog.Uint64("attempt", uint64(at.Attempt)),
+		slog.Duration("latency", at.Latency),
+	}
+
+	level := slog.LevelDebug
+	if at.Response != nil {
+		attrs = append(attrs, slog.Int("status", at.Response.StatusCode))
+	}
+	if at.Err != nil {
+		level = slog.LevelWarn
+
+		var lferr *LastFMError
+		if errors.As(at.Err, &lferr) {
+			attrs = append(attrs, slog.Int("error_code", int(lferr.Code)))
+		}
+		attrs = append(attrs, slog.String("error", redactError(at.Err)))
+	}
+
+	a.Logger.LogAttrs(ctx, level, "lastfm request", attrs...)
+
+	if a.Logger.Enabled(ctx, slog.LevelDebug) {
+		a.Logger.LogAttrs(ctx, slog.LevelDebug, "lastfm request body",
+			slog.String("method", params.Get("method")),
+			slog.Uint64("attempt", uint64(at.Attempt)),
+			slog.String("request", RedactParams(params).Encode()),
+			slog.String("response", RedactBody(string(raw))),
+		)
+	}
+}
+
+// redactError returns the message of the given error, with credentials in the
+// URL of a *url.Error redacted.
+func redactError(err error) string {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err.Error()
+	}
+
+	redacted := *uerr
+	redacted.URL = RedactURL(uerr.URL)
+
+	return strings.Replace(err.Error(), uerr.Error(), redacted.Error(), 1)
+}
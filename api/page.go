@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"iter"
+)
+
+// pageFetcher fetches a single page of a paginated API response.
+type pageFetcher[P any] func(ctx context.Context, page uint) (*P, error)
+
+// pageItems returns the items of a page, along with the page number and the
+// total number of pages reported by the response.
+type pageItems[P, T any] func(res *P) (items []T, page, totalPages int)
+
+// paginate returns an iterator over the items of every page of a paginated
+// API response, starting at the given page. Pages are fetched lazily as the
+// iterator advances, so breaking out of the loop stops further requests.
+// Iteration stops after the last page, or when the response reports an
+// earlier page than the one requested, so it ends even if the API ignores the
+// requested page. If
+// fetching a page fails, the error is yielded with the zero value of T and
+// iteration stops.
+func paginate[P, T any](
+	ctx context.Context, start uint, fetch pageFetcher[P], items pageItems[P, T]) iter.Seq2[T, error] {
+
+	return func(yield func(T, error) bool) {
+		for page := max(start, 1); ; page++ {
+			res, err := fetch(ctx, page)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			list, current, total := items(res)
+			if current > 0 && uint(current) < page {
+				// The page was ignored or clamped, and the response repeats
+				// an earlier page.
+				return
+			}
+
+			for _, item := range list {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if len(list) == 0 || page >= uint(max(total, 0)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the default upper bounds, in seconds, of the
+// buckets of the call duration histogram of PrometheusMetrics.
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// PrometheusMetrics is a Metrics implementation that keeps counters and
+// histograms of calls to the API in memory, and serves them in the Prometheus
+// text exposition format. It's an http.Handler, so it can be served directly,
+// e.g. with http.Handle("/metrics", metrics).
+//
+// The following metrics are exported:
+//   - lastfm_calls_total: calls by method and result, "ok" or "error".
+//   - lastfm_errors_total: failed calls by method and Last.fm error code, or
+//     HTTP status code for failures without an error code.
+//   - lastfm_retries_total: retries by method. Retries are only counted for
+//     the call that sent a coalesced request.
+//   - lastfm_coalesced_total: calls by method that shared the request of an
+//     identical call in flight.
+//   - lastfm_call_duration_seconds: a histogram of call durations by method.
+type PrometheusMetrics struct {
+	mu        sync.Mutex
+	buckets   []float64
+	calls     map[[2]string]uint64
+	errors    map[[2]string]uint64
+	retries   map[string]uint64
+	coalesced map[string]uint64
+	latency   map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheusMetrics returns a new PrometheusMetrics with the given
+// histogram buckets, in seconds. If no buckets are given,
+// DefaultLatencyBuckets are used.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	return &PrometheusMetrics{
+		buckets:   buckets,
+		calls:     make(map[[2]string]uint64),
+		errors:    make(map[[2]string]uint64),
+		retries:   make(map[string]uint64),
+		coalesced: make(map[string]uint64),
+		latency:   make(map[string]*histogram),
+	}
+}
+
+// RecordCall records the stats of a completed call.
+func (m *PrometheusMetrics) RecordCall(stats CallStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	method := stats.Method.String()
+
+	result := "ok"
+	if stats.Err != nil {
+		result = "error"
+
+		code := "none"
+		switch {
+		case stats.ErrorCode != NoError:
+			code = strconv.Itoa(int(stats.ErrorCode))
+		case stats.StatusCode != 0:
+			code = "http_" + strconv.Itoa(stats.StatusCode)
+		}
+		m.errors[[2]string{method, code}]++
+	}
+	m.calls[[2]string{method, result}]++
+
+	if r := stats.Retries(); r > 0 {
+		m.retries[method] += uint64(r)
+	}
+	if stats.Coalesced {
+		m.coalesced[method]++
+	}
+
+	h, ok := m.latency[method]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[method] = h
+	}
+
+	sec := stats.Duration.Seconds()
+	for i, le := range m.buckets {
+		if sec <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += sec
+	h.count++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	writeHeader(&b, "lastfm_calls_total", "counter", "Total calls to the Last.fm API.")
+	for _, k := range slices.SortedFunc(maps.Keys(m.calls), compareLabels) {
+		fmt.Fprintf(&b, "lastfm_calls_total{method=%q,result=%q} %d\n", k[0], k[1], m.calls[k])
+	}
+
+	writeHeader(&b, "lastfm_errors_total", "counter", "Total failed calls to the Last.fm API.")
+	for _, k := range slices.SortedFunc(maps.Keys(m.errors), compareLabels) {
+		fmt.Fprintf(&b, "lastfm_errors_total{method=%q,code=%q} %d\n", k[0], k[1], m.errors[k])
+	}
+
+	writeHeader(&b, "lastfm_retries_total", "counter", "Total retries of calls to the Last.fm API.")
+	for _, method := range slices.Sorted(maps.Keys(m.retries)) {
+		fmt.Fprintf(&b, "lastfm_retries_total{method=%q} %d\n", method, m.retries[method])
+	}
+
+	writeHeader(&b, "lastfm_coalesced_total", "counter",
+		"Total calls to the Last.fm API that shared an identical request in flight.")
+	for _, method := range slices.Sorted(maps.Keys(m.coalesced)) {
+		fmt.Fprintf(&b, "lastfm_coalesced_total{method=%q} %d\n", method, m.coalesced[method])
+	}
+
+	writeHeader(&b, "lastfm_call_duration_seconds", "histogram",
+		"Duration of calls to the Last.fm API, including retries.")
+	for _, method := range slices.Sorted(maps.Keys(m.latency)) {
+		h := m.latency[method]
+		for i, le := range m.buckets {
+			fmt.Fprintf(&b, "lastfm_call_duration_seconds_bucket{method=%q,le=%q} %d\n",
+				method, strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "lastfm_call_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n",
+			method, h.count)
+		fmt.Fprintf(&b, "lastfm_call_duration_seconds_sum{method=%q} %g\n", method, h.sum)
+		fmt.Fprintf(&b, "lastfm_call_duration_seconds_count{method=%q} %d\n", method, h.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func compareLabels(a, b [2]string) int {
+	if c := strings.Compare(a[0], b[0]); c != 0 {
+		return c
+	}
+	return strings.Compare(a[1], b[1])
+}
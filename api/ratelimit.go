@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimit is the number of requests per second allowed by the
+	// Last.fm API terms of service for a single API key.
+	DefaultRateLimit = 5
+	// DefaultRateBurst is the default number of requests that can be made at
+	// once before the rate limit applies.
+	DefaultRateBurst = 5
+)
+
+// ErrRateLimited is returned by a fail-fast RateLimiter when a request would
+// exceed the rate limit.
+var ErrRateLimited = errors.New("client rate limit exceeded")
+
+// Limiter limits the rate of requests made to the API. Wait is called before
+// every request attempt, including retries.
+type Limiter interface {
+	// Wait blocks until a request may be made, or returns an error if the
+	// request may not be made, or the context is done first.
+	Wait(ctx context.Context) error
+}
+
+// RateLimiter is a token bucket Limiter. Tokens are added at a fixed rate up
+// to the size of the bucket, and each request consumes a token.
+//
+// A RateLimiter is safe for concurrent use, and the same RateLimiter should be
+// shared between all API instances that use the same API key, so that their
+// requests count towards the same limit.
+type RateLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	failFast bool
+}
+
+// NewRateLimiter returns a new RateLimiter that allows rate requests per
+// second, with bursts of up to burst requests. The bucket starts full. A rate
+// of zero or less doesn't limit requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// NewDefaultRateLimiter returns a new RateLimiter with the default Last.fm
+// rate limit of DefaultRateLimit requests per second.
+func NewDefaultRateLimiter() *RateLimiter {
+	return NewRateLimiter(DefaultRateLimit, DefaultRateBurst)
+}
+
+var (
+	keyLimitersMu sync.Mutex
+	keyLimiters   = map[string]*RateLimiter{}
+)
+
+// KeyRateLimiter returns the process-wide RateLimiter for the given API key,
+// creating it with the default rate limit if it doesn't exist yet. This makes
+// it easy for independent clients using the same API key to share a limit.
+func KeyRateLimiter(apiKey string) *RateLimiter {
+	keyLimitersMu.Lock()
+	defer keyLimitersMu.Unlock()
+
+	l, ok := keyLimiters[apiKey]
+	if !ok {
+		l = NewDefaultRateLimiter()
+		keyLimiters[apiKey] = l
+	}
+
+	return l
+}
+
+// SetFailFast sets whether Wait returns ErrRateLimited immediately instead of
+// blocking when no tokens are available.
+func (l *RateLimiter) SetFailFast(failFast bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failFast = failFast
+}
+
+// Allow reports whether a request may be made now, consuming a token if so.
+// It never blocks.
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
+
+// Wait implements the Limiter interface for RateLimiter. It blocks until a
+// token is available, unless the limiter is fail-fast, in which case it
+// returns ErrRateLimited. Waiting requests are served in the order they
+// arrive.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	l.refill()
+
+	if l.tokens >= 1 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	if l.failFast {
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+
+	// Reserve a token ahead of time and wait for the deficit to refill.
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if err := sleep(ctx, wait); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+func (l *RateLimiter) refill() {
+	now := time.Now()
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+
+	l.tokens = min(l.burst, l.tokens+elapsed*l.rate)
+}
@@ -111,8 +111,17 @@ func (lf *LFMWrapper) UnmarshalInnerXML(dest any) error {
 
 // LastFMError represents an error returned by Last.fm.
 type LastFMError struct {
-	Code      ErrorCode `xml:"code,attr"`
-	Message   string    `xml:",chardata"`
+	Code    ErrorCode `xml:"code,attr"`
+	Message string    `xml:",chardata"`
+	// Method is the API method of the request that returned the error, if
+	// known.
+	Method APIMethod `xml:"-"`
+	// Attempts is the number of attempts made at the request that returned
+	// the error, if known.
+	Attempts uint `xml:"-"`
+	// Response is the raw response that contained the error, if responses are
+	// captured. See API.CaptureResponses and WithResponse.
+	Response  *ResponseInfo `xml:"-"`
 	httpError *HTTPError
 }
 
@@ -130,7 +139,9 @@ func (e *LastFMError) Error() string {
 	return fmt.Sprintf("Last.fm Error: %d - %s", e.Code, e.Message)
 }
 
-// Is checks if the error matches the target error.
+// Is checks if the error matches the target error. Errors match if they have
+// the same code, so Is can be used with the sentinel errors, such as
+// ErrInvalidSessionKeyError.
 func (e *LastFMError) Is(target error) bool {
 	if t, ok := target.(*LastFMError); ok {
 		return e.IsCode(t.Code)
@@ -177,6 +188,15 @@ func (e *LastFMError) ShouldRetry() bool {
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	// Method is the API method of the request that returned the error, if
+	// known.
+	Method APIMethod
+	// Attempts is the number of attempts made at the request that returned
+	// the error, if known.
+	Attempts uint
+	// Response is the raw response that caused the error, if responses are
+	// captured. See API.CaptureResponses and WithResponse.
+	Response *ResponseInfo
 }
 
 // NewHTTPError creates a new HTTPError instance from an HTTP response.
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+	DefaultRetryMaxWait   = 2 * time.Minute
+)
+
+// RetryAttempt describes a failed request attempt. It is passed to a
+// RetryPolicy to decide whether the request should be retried, and to the
+// OnRetry hook of the API when a retry is scheduled.
+type RetryAttempt struct {
+	// Attempt is the number of the attempt that failed, starting at 1.
+	Attempt uint
+	// Response is the HTTP response of the failed attempt, or nil if no
+	// response was received. The response body has already been closed.
+	Response *http.Response
+	// Err is the error that caused the attempt to fail. This is either the
+	// error returned by HTTPClient.Do, a *LastFMError, an *HTTPError, or an
+	// error encountered while decoding the response.
+	Err error
+	// Waited is the total time spent waiting between previous attempts.
+	Waited time.Duration
+	// Latency is the time the attempt took, from sending the request to
+	// reading the response.
+	Latency time.Duration
+}
+
+// RetryPolicy decides whether a failed request attempt should be retried, and
+// how long to wait before retrying it. The maximum number of attempts is
+// still bounded by the Retries field of the API.
+type RetryPolicy interface {
+	// Retry returns the duration to wait before retrying the failed attempt,
+	// and whether the request should be retried at all.
+	Retry(attempt RetryAttempt) (time.Duration, bool)
+}
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as
+// retry policies.
+type RetryPolicyFunc func(attempt RetryAttempt) (time.Duration, bool)
+
+// Retry calls f(attempt).
+func (f RetryPolicyFunc) Retry(attempt RetryAttempt) (time.Duration, bool) {
+	return f(attempt)
+}
+
+// NoBackoff is a RetryPolicy that retries retryable failures immediately,
+// without waiting between attempts. It is used when the API has no retry
+// policy set.
+var NoBackoff RetryPolicy = RetryPolicyFunc(func(at RetryAttempt) (time.Duration, bool) {
+	return 0, IsRetryable(at.Response, at.Err)
+})
+
+// ExponentialBackoff is a RetryPolicy that retries retryable failures with
+// exponentially increasing delays and full jitter. The delay before retry n is
+// a random duration between 0 and min(MaxDelay, BaseDelay * 2^(n-1)).
+//
+// If the response contains a Retry-After header, the delay it specifies is
+// used instead. The request is not retried if waiting would exceed MaxWait in
+// total.
+type ExponentialBackoff struct {
+	// BaseDelay is the upper bound of the delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the upper bound of the delay before any single retry.
+	MaxDelay time.Duration
+	// MaxWait caps the total time spent waiting between attempts. Zero means
+	// no cap.
+	MaxWait time.Duration
+}
+
+// NewExponentialBackoff returns a new ExponentialBackoff retry policy with
+// the default delays.
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		BaseDelay: DefaultRetryBaseDelay,
+		MaxDelay:  DefaultRetryMaxDelay,
+		MaxWait:   DefaultRetryMaxWait,
+	}
+}
+
+// Retry implements the RetryPolicy interface for ExponentialBackoff.
+func (b ExponentialBackoff) Retry(at RetryAttempt) (time.Duration, bool) {
+	if !IsRetryable(at.Response, at.Err) {
+		return 0, false
+	}
+
+	delay, ok := RetryAfter(at.Response)
+	if !ok {
+		delay = b.Backoff(at.Attempt)
+	}
+
+	if b.MaxWait > 0 && at.Waited+delay > b.MaxWait {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// Backoff returns a random delay with full jitter for the given retry
+// attempt, starting at 1.
+func (b ExponentialBackoff) Backoff(attempt uint) time.Duration {
+	ceil := b.BaseDelay
+	for i := uint(1); i < attempt && (b.MaxDelay <= 0 || ceil < b.MaxDelay); i++ {
+		ceil *= 2
+	}
+	if b.MaxDelay > 0 && ceil > b.MaxDelay {
+		ceil = b.MaxDelay
+	}
+	if ceil <= 0 {
+		return 0
+	}
+
+	return rand.N(ceil + 1)
+}
+
+// IsRetryable reports whether a request attempt that produced the given
+// response and error is worth retrying. This is the case for HTTP 429 and 5xx
+// responses, Last.fm errors for which LastFMError.ShouldRetry returns true,
+// and transient network errors when no response was received.
+func IsRetryable(res *http.Response, err error) bool {
+	if res == nil {
+		return IsTransientNetworkError(err)
+	}
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return true
+	}
+
+	var lferr *LastFMError
+	if errors.As(err, &lferr) {
+		return lferr.ShouldRetry()
+	}
+
+	return false
+}
+
+// IsTransientNetworkError reports whether err is a network error returned by
+// an HTTP client that is likely to succeed if the request is retried, such as
+// timeouts, refused or reset connections, and connections closed before a
+// complete response was received. Context cancellation is never considered
+// transient.
+func IsTransientNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	// Other network errors, such as invalid addresses, unknown networks or
+	// TLS failures, will occur again, unless they are timeouts.
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryAfter returns the delay requested by the Retry-After header of the
+// response, if present. The header may contain either a number of seconds or
+// an HTTP date.
+func RetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if sec < 0 {
+			return 0, false
+		}
+		return time.Duration(sec) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+// sleep waits for the given duration or until the context is done, whichever
+// happens first. It returns the context error if the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
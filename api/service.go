@@ -0,0 +1,58 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+// Service describes a scrobbling service that implements the Last.fm API, such
+// as Last.fm itself or Libre.fm. Use API.SetService to point an API client at
+// a service.
+type Service struct {
+	// Name is the human-readable name of the service.
+	Name string
+	// Endpoint is the URL of the service's API endpoint, e.g.
+	// "https://ws.audioscrobbler.com/2.0/".
+	Endpoint string
+	// AuthEndpoint is the URL users are sent to in order to authorize an
+	// application, e.g. "https://www.last.fm/api/auth". Empty if the service
+	// doesn't support web or desktop authentication.
+	AuthEndpoint string
+}
+
+var (
+	// LastFM is the Last.fm service.
+	LastFM = Service{
+		Name:         "Last.fm",
+		Endpoint:     BaseEndpoint + "/" + Version + "/",
+		AuthEndpoint: lastfm.AuthURL,
+	}
+	// LibreFM is the Libre.fm service.
+	LibreFM = GNUFM("https://libre.fm")
+)
+
+// GNUFM returns the Service of a GNU FM server hosted at the given base URL,
+// e.g. "https://libre.fm".
+func GNUFM(baseURL string) Service {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return Service{
+		Name:         "GNU FM",
+		Endpoint:     baseURL + "/2.0/",
+		AuthEndpoint: baseURL + "/api/auth/",
+	}
+}
+
+// Maloja returns the Service of the Last.fm-compatible API of a Maloja server
+// hosted at the given base URL. Maloja doesn't support web or desktop
+// authentication, so sessions must be created with Client.Login, using a
+// Maloja API key as the password.
+func Maloja(baseURL string) Service {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	return Service{
+		Name:     "Maloja",
+		Endpoint: baseURL + "/apis/audioscrobbler/",
+	}
+}
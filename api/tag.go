@@ -1,6 +1,11 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+	"iter"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Tag struct {
 	api *API
@@ -13,49 +18,177 @@ func NewTag(api *API) *Tag {
 
 // Info returns the information of a tag.
 func (t Tag) Info(params lastfm.TagInfoParams) (*lastfm.TagInfo, error) {
+	return t.InfoContext(context.Background(), params)
+}
+
+// InfoContext is like Info but uses the given context for the request.
+func (t Tag) InfoContext(
+	ctx context.Context, params lastfm.TagInfoParams) (*lastfm.TagInfo, error) {
+
 	var res lastfm.TagInfo
-	return &res, t.api.Get(&res, TagGetInfoMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TagGetInfoMethod, params)
 }
 
 // Similar returns tags similar to the given tag.
 func (t Tag) Similar(tag string) (*lastfm.SimilarTags, error) {
+	return t.SimilarContext(context.Background(), tag)
+}
+
+// SimilarContext is like Similar but uses the given context for the request.
+func (t Tag) SimilarContext(ctx context.Context, tag string) (*lastfm.SimilarTags, error) {
 	var res lastfm.SimilarTags
 	p := lastfm.TagSimilarParams{Tag: tag}
-	return &res, t.api.Get(&res, TagGetSimilarMethod, p)
+	return &res, t.api.GetContext(ctx, &res, TagGetSimilarMethod, p)
 }
 
 // TopAlbums returns the top albums tagged with the given tag.
 func (t Tag) TopAlbums(params lastfm.TagTopAlbumsParams) (*lastfm.TagTopAlbums, error) {
+	return t.TopAlbumsContext(context.Background(), params)
+}
+
+// TopAlbumsContext is like TopAlbums but uses the given context for the
+// request.
+func (t Tag) TopAlbumsContext(
+	ctx context.Context, params lastfm.TagTopAlbumsParams) (*lastfm.TagTopAlbums, error) {
+
 	var res lastfm.TagTopAlbums
-	return &res, t.api.Get(&res, TagGetTopAlbumsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TagGetTopAlbumsMethod, params)
+}
+
+// TopAlbumsAll returns an iterator over the top albums tagged with the given
+// tag on every page, starting from params.Page.
+func (t Tag) TopAlbumsAll(params lastfm.TagTopAlbumsParams) iter.Seq2[lastfm.TagTopAlbum, error] {
+	return t.TopAlbumsAllContext(context.Background(), params)
+}
+
+// TopAlbumsAllContext is like TopAlbumsAll but uses the given context for the
+// requests.
+func (t Tag) TopAlbumsAllContext(
+	ctx context.Context, params lastfm.TagTopAlbumsParams) iter.Seq2[lastfm.TagTopAlbum, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.TagTopAlbums, error) {
+		p := params
+		p.Page = page
+		return t.TopAlbumsContext(ctx, p)
+	}
+	items := func(res *lastfm.TagTopAlbums) ([]lastfm.TagTopAlbum, int, int) {
+		return res.Albums, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopArtists returns the top artists tagged with the given tag.
 func (t Tag) TopArtists(params lastfm.TagTopArtistsParams) (*lastfm.TagTopArtists, error) {
+	return t.TopArtistsContext(context.Background(), params)
+}
+
+// TopArtistsContext is like TopArtists but uses the given context for the
+// request.
+func (t Tag) TopArtistsContext(
+	ctx context.Context, params lastfm.TagTopArtistsParams) (*lastfm.TagTopArtists, error) {
+
 	var res lastfm.TagTopArtists
-	return &res, t.api.Get(&res, TagGetTopArtistsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TagGetTopArtistsMethod, params)
+}
+
+// TopArtistsAll returns an iterator over the top artists tagged with the given
+// tag on every page, starting from params.Page.
+func (t Tag) TopArtistsAll(
+	params lastfm.TagTopArtistsParams) iter.Seq2[lastfm.TagTopArtist, error] {
+
+	return t.TopArtistsAllContext(context.Background(), params)
+}
+
+// TopArtistsAllContext is like TopArtistsAll but uses the given context for the
+// requests.
+func (t Tag) TopArtistsAllContext(
+	ctx context.Context, params lastfm.TagTopArtistsParams) iter.Seq2[lastfm.TagTopArtist, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.TagTopArtists, error) {
+		p := params
+		p.Page = page
+		return t.TopArtistsContext(ctx, p)
+	}
+	items := func(res *lastfm.TagTopArtists) ([]lastfm.TagTopArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopTags returns the top tags on Last.fm.
 func (t Tag) TopTags() (*lastfm.TagTopTags, error) {
-	return t.TopTagsLimit(nil)
+	return t.TopTagsContext(context.Background())
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (t Tag) TopTagsContext(ctx context.Context) (*lastfm.TagTopTags, error) {
+	return t.TopTagsLimitContext(ctx, nil)
 }
 
 // TopTagsLimit returns the top tags on Last.fm, with optional limit and offset.
 func (t Tag) TopTagsLimit(params *lastfm.TagTopTagsParams) (*lastfm.TagTopTags, error) {
+	return t.TopTagsLimitContext(context.Background(), params)
+}
+
+// TopTagsLimitContext is like TopTagsLimit but uses the given context for the
+// request.
+func (t Tag) TopTagsLimitContext(
+	ctx context.Context, params *lastfm.TagTopTagsParams) (*lastfm.TagTopTags, error) {
+
 	var res lastfm.TagTopTags
-	return &res, t.api.Get(&res, TagGetTopTagsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TagGetTopTagsMethod, params)
 }
 
 // TopTracks returns the top tracks tagged with the given tag.
 func (t Tag) TopTracks(params lastfm.TagTopTracksParams) (*lastfm.TagTopTracks, error) {
+	return t.TopTracksContext(context.Background(), params)
+}
+
+// TopTracksContext is like TopTracks but uses the given context for the
+// request.
+func (t Tag) TopTracksContext(
+	ctx context.Context, params lastfm.TagTopTracksParams) (*lastfm.TagTopTracks, error) {
+
 	var res lastfm.TagTopTracks
-	return &res, t.api.Get(&res, TagGetTopTracksMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TagGetTopTracksMethod, params)
+}
+
+// TopTracksAll returns an iterator over the top tracks tagged with the given
+// tag on every page, starting from params.Page.
+func (t Tag) TopTracksAll(params lastfm.TagTopTracksParams) iter.Seq2[lastfm.TagTopTrack, error] {
+	return t.TopTracksAllContext(context.Background(), params)
+}
+
+// TopTracksAllContext is like TopTracksAll but uses the given context for the
+// requests.
+func (t Tag) TopTracksAllContext(
+	ctx context.Context, params lastfm.TagTopTracksParams) iter.Seq2[lastfm.TagTopTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.TagTopTracks, error) {
+		p := params
+		p.Page = page
+		return t.TopTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.TagTopTracks) ([]lastfm.TagTopTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // WeeklyChartList returns the weekly chart list of a tag.
 func (t Tag) WeeklyChartList(tag string) (*lastfm.TagWeeklyChartList, error) {
+	return t.WeeklyChartListContext(context.Background(), tag)
+}
+
+// WeeklyChartListContext is like WeeklyChartList but uses the given context for
+// the request.
+func (t Tag) WeeklyChartListContext(
+	ctx context.Context, tag string) (*lastfm.TagWeeklyChartList, error) {
+
 	var res lastfm.TagWeeklyChartList
 	p := lastfm.TagWeeklyChartListParams{Tag: tag}
-	return &res, t.api.Get(&res, TagGetWeeklyChartListMethod, p)
+	return &res, t.api.GetContext(ctx, &res, TagGetWeeklyChartListMethod, p)
 }
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// CallStats describes a completed call to the API, for tracing and metrics.
+type CallStats struct {
+	// Method is the API method called.
+	Method APIMethod
+	// StatusCode is the HTTP status code of the last attempt, or 0 if no
+	// response was received or the response was cached.
+	StatusCode int
+	// Attempts is the number of attempts made, or 0 if the response was
+	// cached or the call was coalesced, so attempts are only counted once.
+	Attempts uint
+	// Coalesced is true if the call shared the request of an identical call
+	// in flight at the same time.
+	Coalesced bool
+	// ErrorCode is the Last.fm error code of the call, if it failed with one.
+	ErrorCode ErrorCode
+	// Err is the error the call failed with, if any.
+	Err error
+	// Duration is the time the call took, including retries.
+	Duration time.Duration
+}
+
+// Retries returns the number of times the call was retried.
+func (s CallStats) Retries() uint {
+	return max(s.Attempts, 1) - 1
+}
+
+// Tracer starts a span for each call to the API. Implement it to trace calls
+// with OpenTelemetry or another tracing library.
+type Tracer interface {
+	// StartSpan starts a span for a call to the given method, and returns a
+	// context containing the span, which is used for the requests of the call.
+	StartSpan(ctx context.Context, method APIMethod) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// End ends the span with the stats of the completed call.
+	End(stats CallStats)
+}
+
+// Metrics records metrics for each call to the API. Implement it to record
+// metrics with OpenTelemetry or another metrics library, or use
+// PrometheusMetrics.
+type Metrics interface {
+	// RecordCall records the stats of a completed call.
+	RecordCall(stats CallStats)
+}
+
+// SetTracer sets the tracer used to trace calls to the API. Pass nil to
+// disable tracing.
+func (a *API) SetTracer(tracer Tracer) {
+	a.Tracer = tracer
+}
+
+// SetMetrics sets the metrics calls to the API are recorded with. Pass nil to
+// disable metrics.
+func (a *API) SetMetrics(metrics Metrics) {
+	a.Metrics = metrics
+}
+
+// observe starts tracing a call, and returns the context to make the call with
+// and a function that ends tracing the call and records its metrics.
+func (a *API) observe(ctx context.Context, call *Call) (context.Context, func(err error)) {
+	if a.Tracer == nil && a.Metrics == nil {
+		return ctx, func(error) {}
+	}
+
+	var span Span
+	if a.Tracer != nil {
+		ctx, span = a.Tracer.StartSpan(ctx, call.Method)
+	}
+
+	start := time.Now()
+
+	return ctx, func(err error) {
+		stats := CallStats{
+			Method:     call.Method,
+			StatusCode: call.StatusCode,
+			Attempts:   call.Attempts,
+			Coalesced:  call.Coalesced,
+			Err:        err,
+			Duration:   time.Since(start),
+		}
+
+		var lferr *LastFMError
+		if errors.As(err, &lferr) {
+			stats.ErrorCode = lferr.Code
+		}
+
+		if span != nil {
+			span.End(stats)
+		}
+		if a.Metrics != nil {
+			a.Metrics.RecordCall(stats)
+		}
+	}
+}
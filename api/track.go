@@ -1,6 +1,10 @@
 package api
 
-import "github.com/twoscott/gobble-fm/lastfm"
+import (
+	"context"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
 
 type Track struct {
 	api *API
@@ -13,76 +17,165 @@ func NewTrack(api *API) *Track {
 
 // Correction returns the track and artist name corrections of a track.
 func (t Track) Correction(artist, track string) (*lastfm.TrackCorrection, error) {
+	return t.CorrectionContext(context.Background(), artist, track)
+}
+
+// CorrectionContext is like Correction but uses the given context for the
+// request.
+func (t Track) CorrectionContext(
+	ctx context.Context, artist, track string) (*lastfm.TrackCorrection, error) {
+
 	var res lastfm.TrackCorrection
 	p := lastfm.TrackCorrectionParams{Artist: artist, Track: track}
-	return &res, t.api.Get(&res, TrackGetCorrectionMethod, p)
+	return &res, t.api.GetContext(ctx, &res, TrackGetCorrectionMethod, p)
 }
 
 // Info returns the information of a track by artist and track name.
 func (t Track) Info(params lastfm.TrackInfoParams) (*lastfm.TrackInfo, error) {
+	return t.InfoContext(context.Background(), params)
+}
+
+// InfoContext is like Info but uses the given context for the request.
+func (t Track) InfoContext(
+	ctx context.Context, params lastfm.TrackInfoParams) (*lastfm.TrackInfo, error) {
+
 	var res lastfm.TrackInfo
-	return &res, t.api.Get(&res, TrackGetInfoMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetInfoMethod, params)
 }
 
 // InfoByMBID returns the information of a track by MBID.
 func (t Track) InfoByMBID(params lastfm.TrackInfoMBIDParams) (*lastfm.TrackInfo, error) {
+	return t.InfoByMBIDContext(context.Background(), params)
+}
+
+// InfoByMBIDContext is like InfoByMBID but uses the given context for the
+// request.
+func (t Track) InfoByMBIDContext(
+	ctx context.Context, params lastfm.TrackInfoMBIDParams) (*lastfm.TrackInfo, error) {
+
 	var res lastfm.TrackInfo
-	return &res, t.api.Get(&res, TrackGetInfoMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetInfoMethod, params)
 }
 
 // UserInfo returns the information of a track for user by artist and track
 // name.
 func (t Track) UserInfo(params lastfm.TrackUserInfoParams) (*lastfm.TrackUserInfo, error) {
+	return t.UserInfoContext(context.Background(), params)
+}
+
+// UserInfoContext is like UserInfo but uses the given context for the request.
+func (t Track) UserInfoContext(
+	ctx context.Context, params lastfm.TrackUserInfoParams) (*lastfm.TrackUserInfo, error) {
+
 	var res lastfm.TrackUserInfo
-	return &res, t.api.Get(&res, TrackGetInfoMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetInfoMethod, params)
 }
 
 // UserInfoByMBID returns the information of a track for user by MBID.
 func (t Track) UserInfoByMBID(
 	params lastfm.TrackUserInfoMBIDParams) (*lastfm.TrackUserInfo, error) {
+	return t.UserInfoByMBIDContext(context.Background(), params)
+}
+
+// UserInfoByMBIDContext is like UserInfoByMBID but uses the given context for
+// the request.
+func (t Track) UserInfoByMBIDContext(
+	ctx context.Context, params lastfm.TrackUserInfoMBIDParams) (*lastfm.TrackUserInfo, error) {
 
 	var res lastfm.TrackUserInfo
-	return &res, t.api.Get(&res, TrackGetInfoMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetInfoMethod, params)
 }
 
 // Similar returns the similar tracks of a track by artist and track name.
 func (t Track) Similar(params lastfm.TrackSimilarParams) (*lastfm.SimilarTracks, error) {
+	return t.SimilarContext(context.Background(), params)
+}
+
+// SimilarContext is like Similar but uses the given context for the request.
+func (t Track) SimilarContext(
+	ctx context.Context, params lastfm.TrackSimilarParams) (*lastfm.SimilarTracks, error) {
+
 	var res lastfm.SimilarTracks
-	return &res, t.api.Get(&res, TrackGetSimilarMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetSimilarMethod, params)
 }
 
 // SimilarByMBID returns the similar tracks of a track by MBID.
 func (t Track) SimilarByMBID(params lastfm.TrackSimilarMBIDParams) (*lastfm.SimilarTracks, error) {
+	return t.SimilarByMBIDContext(context.Background(), params)
+}
+
+// SimilarByMBIDContext is like SimilarByMBID but uses the given context for the
+// request.
+func (t Track) SimilarByMBIDContext(
+	ctx context.Context, params lastfm.TrackSimilarMBIDParams) (*lastfm.SimilarTracks, error) {
+
 	var res lastfm.SimilarTracks
-	return &res, t.api.Get(&res, TrackGetSimilarMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetSimilarMethod, params)
 }
 
 // Tags returns the tags of a track by artist and track name.
 func (t Track) Tags(params lastfm.TrackTagsParams) (*lastfm.TrackTags, error) {
+	return t.TagsContext(context.Background(), params)
+}
+
+// TagsContext is like Tags but uses the given context for the request.
+func (t Track) TagsContext(
+	ctx context.Context, params lastfm.TrackTagsParams) (*lastfm.TrackTags, error) {
+
 	var res lastfm.TrackTags
-	return &res, t.api.Get(&res, TrackGetTagsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetTagsMethod, params)
 }
 
 // TagsByMBID returns the tags of a track by MBID.
 func (t Track) TagsByMBID(params lastfm.TrackTagsMBIDParams) (*lastfm.TrackTags, error) {
+	return t.TagsByMBIDContext(context.Background(), params)
+}
+
+// TagsByMBIDContext is like TagsByMBID but uses the given context for the
+// request.
+func (t Track) TagsByMBIDContext(
+	ctx context.Context, params lastfm.TrackTagsMBIDParams) (*lastfm.TrackTags, error) {
+
 	var res lastfm.TrackTags
-	return &res, t.api.Get(&res, TrackGetTagsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetTagsMethod, params)
 }
 
 // TopTags returns the top tags of a track by artist and track name.
 func (t Track) TopTags(params lastfm.TrackTopTagsParams) (*lastfm.TrackTopTags, error) {
+	return t.TopTagsContext(context.Background(), params)
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (t Track) TopTagsContext(
+	ctx context.Context, params lastfm.TrackTopTagsParams) (*lastfm.TrackTopTags, error) {
+
 	var res lastfm.TrackTopTags
-	return &res, t.api.Get(&res, TrackGetTopTagsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetTopTagsMethod, params)
 }
 
 // TopTagsByMBID returns the top tags of a track by MBID.
 func (t Track) TopTagsByMBID(params lastfm.TrackTopTagsMBIDParams) (*lastfm.TrackTopTags, error) {
+	return t.TopTagsByMBIDContext(context.Background(), params)
+}
+
+// TopTagsByMBIDContext is like TopTagsByMBID but uses the given context for the
+// request.
+func (t Track) TopTagsByMBIDContext(
+	ctx context.Context, params lastfm.TrackTopTagsMBIDParams) (*lastfm.TrackTopTags, error) {
+
 	var res lastfm.TrackTopTags
-	return &res, t.api.Get(&res, TrackGetTopTagsMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackGetTopTagsMethod, params)
 }
 
 // Search searches for tracks by track name, and optionally artist name.
 func (t Track) Search(params lastfm.TrackSearchParams) (*lastfm.TrackSearchResult, error) {
+	return t.SearchContext(context.Background(), params)
+}
+
+// SearchContext is like Search but uses the given context for the request.
+func (t Track) SearchContext(
+	ctx context.Context, params lastfm.TrackSearchParams) (*lastfm.TrackSearchResult, error) {
+
 	var res lastfm.TrackSearchResult
-	return &res, t.api.Get(&res, TrackSearchMethod, params)
+	return &res, t.api.GetContext(ctx, &res, TrackSearchMethod, params)
 }
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"context"
+	"iter"
+
 	"github.com/twoscott/gobble-fm/lastfm"
 )
 
@@ -25,129 +28,514 @@ func NewUser(api *API) *User {
 
 // Friends returns the friends of a user.
 func (u User) Friends(params lastfm.FriendsParams) (*lastfm.Friends, error) {
+	return u.FriendsContext(context.Background(), params)
+}
+
+// FriendsContext is like Friends but uses the given context for the request.
+func (u User) FriendsContext(
+	ctx context.Context, params lastfm.FriendsParams) (*lastfm.Friends, error) {
+
 	var res lastfm.Friends
-	return &res, u.api.Get(&res, UserGetFriendsMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetFriendsMethod, params)
+}
+
+// FriendsAll returns an iterator over the friends of a user on every page,
+// starting from params.Page.
+func (u User) FriendsAll(params lastfm.FriendsParams) iter.Seq2[lastfm.Friend, error] {
+	return u.FriendsAllContext(context.Background(), params)
+}
+
+// FriendsAllContext is like FriendsAll but uses the given context for the
+// requests.
+func (u User) FriendsAllContext(
+	ctx context.Context, params lastfm.FriendsParams) iter.Seq2[lastfm.Friend, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.Friends, error) {
+		p := params
+		p.Page = page
+		return u.FriendsContext(ctx, p)
+	}
+	items := func(res *lastfm.Friends) ([]lastfm.Friend, int, int) {
+		return res.Users, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // Info returns the information of a user.
 func (u User) Info(user string) (*lastfm.UserInfo, error) {
+	return u.InfoContext(context.Background(), user)
+}
+
+// InfoContext is like Info but uses the given context for the request.
+func (u User) InfoContext(ctx context.Context, user string) (*lastfm.UserInfo, error) {
 	var res lastfm.UserInfo
 	p := lastfm.UserInfoParams{User: user}
-	return &res, u.api.Get(&res, UserGetInfoMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetInfoMethod, p)
 }
 
 // LovedTracks returns the loved tracks of a user.
 func (u User) LovedTracks(params lastfm.LovedTracksParams) (*lastfm.LovedTracks, error) {
+	return u.LovedTracksContext(context.Background(), params)
+}
+
+// LovedTracksContext is like LovedTracks but uses the given context for the
+// request.
+func (u User) LovedTracksContext(
+	ctx context.Context, params lastfm.LovedTracksParams) (*lastfm.LovedTracks, error) {
+
 	var res lastfm.LovedTracks
-	return &res, u.api.Get(&res, UserGetLovedTracksMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetLovedTracksMethod, params)
+}
+
+// LovedTracksAll returns an iterator over the loved tracks of a user on every
+// page, starting from params.Page.
+func (u User) LovedTracksAll(params lastfm.LovedTracksParams) iter.Seq2[lastfm.LovedTrack, error] {
+	return u.LovedTracksAllContext(context.Background(), params)
+}
+
+// LovedTracksAllContext is like LovedTracksAll but uses the given context for
+// the requests.
+func (u User) LovedTracksAllContext(
+	ctx context.Context, params lastfm.LovedTracksParams) iter.Seq2[lastfm.LovedTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.LovedTracks, error) {
+		p := params
+		p.Page = page
+		return u.LovedTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.LovedTracks) ([]lastfm.LovedTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // RecentTrack returns the most recent track of a user. This is a convenience
 // method that calls RecentTracks with limit=1.
 func (u User) RecentTrack(user string) (*lastfm.RecentTrack, error) {
+	return u.RecentTrackContext(context.Background(), user)
+}
+
+// RecentTrackContext is like RecentTrack but uses the given context for the
+// request.
+func (u User) RecentTrackContext(ctx context.Context, user string) (*lastfm.RecentTrack, error) {
 	var res lastfm.RecentTrack
 	p := lastfm.RecentTracksParams{User: user, Limit: 1}
-	return &res, u.api.Get(&res, UserGetRecentTracksMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetRecentTracksMethod, p)
 }
 
 // RecentTracks returns the recent tracks of a user.
 func (u User) RecentTracks(params lastfm.RecentTracksParams) (*lastfm.RecentTracks, error) {
+	return u.RecentTracksContext(context.Background(), params)
+}
+
+// RecentTracksContext is like RecentTracks but uses the given context for the
+// request.
+func (u User) RecentTracksContext(
+	ctx context.Context, params lastfm.RecentTracksParams) (*lastfm.RecentTracks, error) {
+
 	var res lastfm.RecentTracks
-	return &res, u.api.Get(&res, UserGetRecentTracksMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetRecentTracksMethod, params)
+}
+
+// RecentTracksAll returns an iterator over the recent tracks of a user on every
+// page, starting from params.Page. The track the user is currently playing, if
+// any, is only yielded once.
+func (u User) RecentTracksAll(params lastfm.RecentTracksParams) iter.Seq2[lastfm.Track, error] {
+	return u.RecentTracksAllContext(context.Background(), params)
+}
+
+// RecentTracksAllContext is like RecentTracksAll but uses the given context for
+// the requests.
+func (u User) RecentTracksAllContext(
+	ctx context.Context, params lastfm.RecentTracksParams) iter.Seq2[lastfm.Track, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.RecentTracks, error) {
+		p := params
+		p.Page = page
+		return u.RecentTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.RecentTracks) ([]lastfm.Track, int, int) {
+		tracks := res.Tracks
+		// The now playing track is included on every page, so only yield it
+		// from the first page.
+		if res.Page > int(max(params.Page, 1)) && len(tracks) > 0 && tracks[0].NowPlaying {
+			tracks = tracks[1:]
+		}
+		return tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // RecentTrackExtended returns the most recent track of a user with extended
 // information. This is a convenience method that calls RecentTracksExtended
 // with limit=1.
 func (u User) RecentTrackExtended(user string) (*lastfm.RecentTrackExtended, error) {
+	return u.RecentTrackExtendedContext(context.Background(), user)
+}
+
+// RecentTrackExtendedContext is like RecentTrackExtended but uses the given
+// context for the request.
+func (u User) RecentTrackExtendedContext(
+	ctx context.Context, user string) (*lastfm.RecentTrackExtended, error) {
+
 	var res lastfm.RecentTrackExtended
 	p := lastfm.RecentTracksParams{User: user, Limit: 1}
 	exp := recentTracksExtendedParams{RecentTracksParams: p, Extended: true}
-	return &res, u.api.Get(&res, UserGetRecentTracksMethod, exp)
+	return &res, u.api.GetContext(ctx, &res, UserGetRecentTracksMethod, exp)
 }
 
 // RecentTracksExtended returns the recent tracks of a user with extended
 // information.
 func (u User) RecentTracksExtended(
 	params lastfm.RecentTracksParams) (*lastfm.RecentTracksExtended, error) {
+	return u.RecentTracksExtendedContext(context.Background(), params)
+}
+
+// RecentTracksExtendedContext is like RecentTracksExtended but uses the given
+// context for the request.
+func (u User) RecentTracksExtendedContext(
+	ctx context.Context, params lastfm.RecentTracksParams) (*lastfm.RecentTracksExtended, error) {
 
 	var res lastfm.RecentTracksExtended
 	exp := recentTracksExtendedParams{RecentTracksParams: params, Extended: true}
-	return &res, u.api.Get(&res, UserGetRecentTracksMethod, exp)
+	return &res, u.api.GetContext(ctx, &res, UserGetRecentTracksMethod, exp)
+}
+
+// RecentTracksExtendedAll returns an iterator over the recent tracks of a user
+// with extended information on every page, starting from params.Page. The track
+// the user is currently playing, if any, is only yielded once.
+func (u User) RecentTracksExtendedAll(
+	params lastfm.RecentTracksParams) iter.Seq2[lastfm.TrackExtended, error] {
+
+	return u.RecentTracksExtendedAllContext(context.Background(), params)
+}
+
+// RecentTracksExtendedAllContext is like RecentTracksExtendedAll but uses the
+// given context for the requests.
+func (u User) RecentTracksExtendedAllContext(
+	ctx context.Context, params lastfm.RecentTracksParams) iter.Seq2[lastfm.TrackExtended, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.RecentTracksExtended, error) {
+		p := params
+		p.Page = page
+		return u.RecentTracksExtendedContext(ctx, p)
+	}
+	items := func(res *lastfm.RecentTracksExtended) ([]lastfm.TrackExtended, int, int) {
+		tracks := res.Tracks
+		// The now playing track is included on every page, so only yield it
+		// from the first page.
+		if res.Page > int(max(params.Page, 1)) && len(tracks) > 0 && tracks[0].NowPlaying {
+			tracks = tracks[1:]
+		}
+		return tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TaggedAlbums returns the albums tagged by a user with the given tag.
 func (u User) TaggedAlbums(params lastfm.UserTagsParams) (*lastfm.UserAlbumTags, error) {
+	return u.TaggedAlbumsContext(context.Background(), params)
+}
+
+// TaggedAlbumsContext is like TaggedAlbums but uses the given context for the
+// request.
+func (u User) TaggedAlbumsContext(
+	ctx context.Context, params lastfm.UserTagsParams) (*lastfm.UserAlbumTags, error) {
+
 	var res lastfm.UserAlbumTags
 	p := userTagsExtendedParams{UserTagsParams: params, Type: lastfm.TagTypeAlbum}
-	return &res, u.api.Get(&res, UserGetPersonalTagsMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetPersonalTagsMethod, p)
+}
+
+// TaggedAlbumsAll returns an iterator over the albums tagged by a user with the
+// given tag on every page, starting from params.Page.
+func (u User) TaggedAlbumsAll(params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedAlbum, error] {
+	return u.TaggedAlbumsAllContext(context.Background(), params)
+}
+
+// TaggedAlbumsAllContext is like TaggedAlbumsAll but uses the given context for
+// the requests.
+func (u User) TaggedAlbumsAllContext(
+	ctx context.Context, params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedAlbum, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserAlbumTags, error) {
+		p := params
+		p.Page = page
+		return u.TaggedAlbumsContext(ctx, p)
+	}
+	items := func(res *lastfm.UserAlbumTags) ([]lastfm.TaggedAlbum, int, int) {
+		return res.Albums, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TaggedArtists returns the artists tagged by a user with the given tag.
 func (u User) TaggedArtists(params lastfm.UserTagsParams) (*lastfm.UserArtistTags, error) {
+	return u.TaggedArtistsContext(context.Background(), params)
+}
+
+// TaggedArtistsContext is like TaggedArtists but uses the given context for the
+// request.
+func (u User) TaggedArtistsContext(
+	ctx context.Context, params lastfm.UserTagsParams) (*lastfm.UserArtistTags, error) {
+
 	var res lastfm.UserArtistTags
 	p := userTagsExtendedParams{UserTagsParams: params, Type: lastfm.TagTypeArtist}
-	return &res, u.api.Get(&res, UserGetPersonalTagsMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetPersonalTagsMethod, p)
+}
+
+// TaggedArtistsAll returns an iterator over the artists tagged by a user with
+// the given tag on every page, starting from params.Page.
+func (u User) TaggedArtistsAll(params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedArtist, error] {
+	return u.TaggedArtistsAllContext(context.Background(), params)
+}
+
+// TaggedArtistsAllContext is like TaggedArtistsAll but uses the given context
+// for the requests.
+func (u User) TaggedArtistsAllContext(
+	ctx context.Context, params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedArtist, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserArtistTags, error) {
+		p := params
+		p.Page = page
+		return u.TaggedArtistsContext(ctx, p)
+	}
+	items := func(res *lastfm.UserArtistTags) ([]lastfm.TaggedArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TaggedTracks returns the tracks tagged by a user with the given tag.
 func (u User) TaggedTracks(params lastfm.UserTagsParams) (*lastfm.UserTrackTags, error) {
+	return u.TaggedTracksContext(context.Background(), params)
+}
+
+// TaggedTracksContext is like TaggedTracks but uses the given context for the
+// request.
+func (u User) TaggedTracksContext(
+	ctx context.Context, params lastfm.UserTagsParams) (*lastfm.UserTrackTags, error) {
+
 	var res lastfm.UserTrackTags
 	p := userTagsExtendedParams{UserTagsParams: params, Type: lastfm.TagTypeTrack}
-	return &res, u.api.Get(&res, UserGetPersonalTagsMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetPersonalTagsMethod, p)
+}
+
+// TaggedTracksAll returns an iterator over the tracks tagged by a user with the
+// given tag on every page, starting from params.Page.
+func (u User) TaggedTracksAll(params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedTrack, error] {
+	return u.TaggedTracksAllContext(context.Background(), params)
+}
+
+// TaggedTracksAllContext is like TaggedTracksAll but uses the given context for
+// the requests.
+func (u User) TaggedTracksAllContext(
+	ctx context.Context, params lastfm.UserTagsParams) iter.Seq2[lastfm.TaggedTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserTrackTags, error) {
+		p := params
+		p.Page = page
+		return u.TaggedTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.UserTrackTags) ([]lastfm.TaggedTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopAlbums returns the top albums of a user.
 func (u User) TopAlbums(params lastfm.UserTopAlbumsParams) (*lastfm.UserTopAlbums, error) {
+	return u.TopAlbumsContext(context.Background(), params)
+}
+
+// TopAlbumsContext is like TopAlbums but uses the given context for the
+// request.
+func (u User) TopAlbumsContext(
+	ctx context.Context, params lastfm.UserTopAlbumsParams) (*lastfm.UserTopAlbums, error) {
+
 	var res lastfm.UserTopAlbums
-	return &res, u.api.Get(&res, UserGetTopAlbumsMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetTopAlbumsMethod, params)
+}
+
+// TopAlbumsAll returns an iterator over the top albums of a user on every page,
+// starting from params.Page.
+func (u User) TopAlbumsAll(
+	params lastfm.UserTopAlbumsParams) iter.Seq2[lastfm.UserTopAlbum, error] {
+
+	return u.TopAlbumsAllContext(context.Background(), params)
+}
+
+// TopAlbumsAllContext is like TopAlbumsAll but uses the given context for the
+// requests.
+func (u User) TopAlbumsAllContext(
+	ctx context.Context, params lastfm.UserTopAlbumsParams) iter.Seq2[lastfm.UserTopAlbum, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserTopAlbums, error) {
+		p := params
+		p.Page = page
+		return u.TopAlbumsContext(ctx, p)
+	}
+	items := func(res *lastfm.UserTopAlbums) ([]lastfm.UserTopAlbum, int, int) {
+		return res.Albums, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopArtists returns the top artists of a user.
 func (u User) TopArtists(params lastfm.UserTopArtistsParams) (*lastfm.UserTopArtists, error) {
+	return u.TopArtistsContext(context.Background(), params)
+}
+
+// TopArtistsContext is like TopArtists but uses the given context for the
+// request.
+func (u User) TopArtistsContext(
+	ctx context.Context, params lastfm.UserTopArtistsParams) (*lastfm.UserTopArtists, error) {
+
 	var res lastfm.UserTopArtists
-	return &res, u.api.Get(&res, UserGetTopArtistsMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetTopArtistsMethod, params)
+}
+
+// TopArtistsAll returns an iterator over the top artists of a user on every
+// page, starting from params.Page.
+func (u User) TopArtistsAll(
+	params lastfm.UserTopArtistsParams) iter.Seq2[lastfm.UserTopArtist, error] {
+
+	return u.TopArtistsAllContext(context.Background(), params)
+}
+
+// TopArtistsAllContext is like TopArtistsAll but uses the given context for the
+// requests.
+func (u User) TopArtistsAllContext(
+	ctx context.Context, params lastfm.UserTopArtistsParams) iter.Seq2[lastfm.UserTopArtist, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserTopArtists, error) {
+		p := params
+		p.Page = page
+		return u.TopArtistsContext(ctx, p)
+	}
+	items := func(res *lastfm.UserTopArtists) ([]lastfm.UserTopArtist, int, int) {
+		return res.Artists, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // TopTags returns the top tags of a user.
 func (u User) TopTags(params lastfm.UserTopTagsParams) (*lastfm.UserTopTags, error) {
+	return u.TopTagsContext(context.Background(), params)
+}
+
+// TopTagsContext is like TopTags but uses the given context for the request.
+func (u User) TopTagsContext(
+	ctx context.Context, params lastfm.UserTopTagsParams) (*lastfm.UserTopTags, error) {
+
 	var res lastfm.UserTopTags
-	return &res, u.api.Get(&res, UserGetTopTagsMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetTopTagsMethod, params)
 }
 
 // TopTracks returns the top tracks of a user.
 func (u User) TopTracks(params lastfm.UserTopTracksParams) (*lastfm.UserTopTracks, error) {
+	return u.TopTracksContext(context.Background(), params)
+}
+
+// TopTracksContext is like TopTracks but uses the given context for the
+// request.
+func (u User) TopTracksContext(
+	ctx context.Context, params lastfm.UserTopTracksParams) (*lastfm.UserTopTracks, error) {
+
 	var res lastfm.UserTopTracks
-	return &res, u.api.Get(&res, UserGetTopTracksMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetTopTracksMethod, params)
+}
+
+// TopTracksAll returns an iterator over the top tracks of a user on every page,
+// starting from params.Page.
+func (u User) TopTracksAll(
+	params lastfm.UserTopTracksParams) iter.Seq2[lastfm.UserTopTrack, error] {
+
+	return u.TopTracksAllContext(context.Background(), params)
+}
+
+// TopTracksAllContext is like TopTracksAll but uses the given context for the
+// requests.
+func (u User) TopTracksAllContext(
+	ctx context.Context, params lastfm.UserTopTracksParams) iter.Seq2[lastfm.UserTopTrack, error] {
+
+	fetch := func(ctx context.Context, page uint) (*lastfm.UserTopTracks, error) {
+		p := params
+		p.Page = page
+		return u.TopTracksContext(ctx, p)
+	}
+	items := func(res *lastfm.UserTopTracks) ([]lastfm.UserTopTrack, int, int) {
+		return res.Tracks, res.Page, res.TotalPages
+	}
+
+	return paginate(ctx, params.Page, fetch, items)
 }
 
 // WeeklyAlbumChart returns the weekly album chart of a user.
 func (u User) WeeklyAlbumChart(
 	params lastfm.WeeklyAlbumChartParams) (*lastfm.WeeklyAlbumChart, error) {
+	return u.WeeklyAlbumChartContext(context.Background(), params)
+}
+
+// WeeklyAlbumChartContext is like WeeklyAlbumChart but uses the given context
+// for the request.
+func (u User) WeeklyAlbumChartContext(
+	ctx context.Context, params lastfm.WeeklyAlbumChartParams) (*lastfm.WeeklyAlbumChart, error) {
 
 	var res lastfm.WeeklyAlbumChart
-	return &res, u.api.Get(&res, UserGetWeeklyAlbumChartMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetWeeklyAlbumChartMethod, params)
 }
 
 // WeeklyArtistChart returns the weekly artist chart of a user.
 func (u User) WeeklyArtistChart(
 	params lastfm.WeeklyArtistChartParams) (*lastfm.WeeklyArtistChart, error) {
+	return u.WeeklyArtistChartContext(context.Background(), params)
+}
+
+// WeeklyArtistChartContext is like WeeklyArtistChart but uses the given context
+// for the request.
+func (u User) WeeklyArtistChartContext(
+	ctx context.Context, params lastfm.WeeklyArtistChartParams) (*lastfm.WeeklyArtistChart, error) {
 
 	var res lastfm.WeeklyArtistChart
-	return &res, u.api.Get(&res, UserGetWeeklyArtistChartMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetWeeklyArtistChartMethod, params)
 }
 
 // WeeklyChartList returns the weekly chart list of a user.
 func (u User) WeeklyChartList(user string) (*lastfm.WeeklyChartList, error) {
+	return u.WeeklyChartListContext(context.Background(), user)
+}
+
+// WeeklyChartListContext is like WeeklyChartList but uses the given context for
+// the request.
+func (u User) WeeklyChartListContext(
+	ctx context.Context, user string) (*lastfm.WeeklyChartList, error) {
+
 	var res lastfm.WeeklyChartList
 	p := lastfm.WeeklyChartListParams{User: user}
-	return &res, u.api.Get(&res, UserGetWeeklyChartListMethod, p)
+	return &res, u.api.GetContext(ctx, &res, UserGetWeeklyChartListMethod, p)
 }
 
 // WeeklyTrackChart returns the weekly track chart of a user.
 func (u User) WeeklyTrackChart(
 	params lastfm.WeeklyTrackChartParams) (*lastfm.WeeklyTrackChart, error) {
+	return u.WeeklyTrackChartContext(context.Background(), params)
+}
+
+// WeeklyTrackChartContext is like WeeklyTrackChart but uses the given context
+// for the request.
+func (u User) WeeklyTrackChartContext(
+	ctx context.Context, params lastfm.WeeklyTrackChartParams) (*lastfm.WeeklyTrackChart, error) {
 
 	var res lastfm.WeeklyTrackChart
-	return &res, u.api.Get(&res, UserGetWeeklyTrackChartMethod, params)
+	return &res, u.api.GetContext(ctx, &res, UserGetWeeklyTrackChartMethod, params)
 }
@@ -6,7 +6,6 @@ import (
 	"os"
 	"time"
 
-	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 	"github.com/twoscott/gobble-fm/session"
 )
@@ -17,40 +16,25 @@ func main() {
 
 	fm := session.NewClientWithTimeout(apiKey, secret, 30)
 
-	// Fetch an unauthorised token from the API.
-	token, err := fm.Auth.Token()
-	if err != nil {
-		fmt.Println("Error getting token:", err)
-		return
+	// Fetch an unauthorised token from the API, and wait for the user to
+	// authorize it. The user must log in and authorize the application at the
+	// authorization URL, which is opened in their web browser.
+	auth := session.NewDesktopAuth(fm)
+	auth.OpenBrowser = true
+	auth.OnURL = func(url string) {
+		fmt.Printf("Visit the following URL to authorize the application: %s\n", url)
 	}
 
-	// Now the token must be authorized by the user. This is done by redirecting
-	// the user to the authorization URL, where they can log in and authorize
-	// the application.
-	url := fm.AuthTokenURL(token)
-
-	// In a real application, you would redirect the user to this URL in their
-	// web browser. For this example, we'll just print it to the console.
-	fmt.Printf("Visit the following URL to authorize the application: %s\n", url)
-
-	// The token can't be used until the user authorizes it.
-	fmt.Println("Once you've authorized the application, press Enter... ")
-	fmt.Scanln()
-
-	// After the user authorizes your app, you can use the token to log in.
-	err = fm.TokenLogin(token)
+	// The token is polled for until the user authorizes it, after which the
+	// session key is set in the client.
+	_, err := auth.Login()
 	if err != nil {
-		var fmerr *api.LastFMError
-		if errors.As(err, &fmerr) {
-			switch fmerr.Code {
-			case api.ErrUnauthorizedToken:
-				fmt.Println("You must authorize the token before using it.")
-			default:
-				fmt.Println("Authorization failed:", err)
-				// ...
-			}
-		} else {
-			fmt.Println(err)
+		switch {
+		case errors.Is(err, session.ErrTokenExpired):
+			fmt.Println("You must authorize the application before the token expires.")
+		default:
+			fmt.Println("Authorization failed:", err)
+			// ...
 		}
 
 		return
@@ -1,12 +1,12 @@
 package main
 
 import (
-	"errors"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
 	"time"
 
-	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 	"github.com/twoscott/gobble-fm/session"
 )
@@ -17,42 +17,38 @@ func main() {
 
 	fm := session.NewClientWithTimeout(apiKey, secret, 30)
 
-	// If you set up a callback URL when applying for your Last.fm API key,
-	// then you can use `Session.AuthURL()` instead, and it will redirect the
-	// user to the callback URL after they authorize your app.
-	url := fm.AuthCallbackURL("https://example.com/callback")
+	// The handler redirects users to Last.fm to authorize your app, and
+	// Last.fm redirects them back to the handler with a `token` query
+	// parameter, which the handler exchanges for a session. It must be served
+	// at the callback URL.
+	//
+	// For example: https://example.com/callback?state=STATE&token=TOKEN
+	auth := session.NewWebAuthHandler(fm, "https://example.com/callback", storeSession)
 
-	// Redirect the user to the auth URL. In a real application, you would
-	// redirect the user to this URL in their web browser.
-	redirectUserToAuthURL(url)
+	// Unauthorized tokens and expired logins are handled with default error
+	// pages. Set ErrorHandler to serve your own, e.g.
+	//
+	//	auth.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+	//		if errors.Is(err, api.ErrUnauthorizedTokenError) {
+	//			// You must authorize the token before using it.
+	//		}
+	//	}
 
-	// After authorising your app, the user will be redirected to the callback
-	// URL with a `token` query parameter. You need to extract this token and
-	// use it to create a session key.
-	// For example: https://example.com/callback?token=TOKEN
-
-	token := "AUTHORISED_TOKEN" // obtained from the callback URL
+	http.Handle("/callback", auth)
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
 
-	// After the user authorizes your app, you can use the token to log in.
-	err := fm.TokenLogin(token)
-	if err != nil {
-		var fmerr *api.LastFMError
-		if errors.As(err, &fmerr) {
-			switch fmerr.Code {
-			case api.ErrUnauthorizedToken:
-				fmt.Println("You must authorize the token before using it.")
-			default:
-				fmt.Println("Authorization failed:", err)
-				// ...
-			}
-		} else {
-			fmt.Println(err)
-		}
+func storeSession(w http.ResponseWriter, r *http.Request, s *lastfm.Session) error {
+	// Store the session key, e.g. in a database. Last.fm session keys don't
+	// expire, so you can use it to make requests on behalf of the user until
+	// they revoke access.
+	fmt.Println("Logged in as", s.Name)
 
-		return
-	}
+	apiKey := os.Getenv("LASTFM_API_KEY")
+	secret := os.Getenv("LASTFM_API_SECRET")
 
-	// Now you can use the session key to make requests on behalf of the user.
+	fm := session.NewClientWithTimeout(apiKey, secret, 30)
+	fm.SetSessionKey(s.Key)
 
 	// For example, to scrobble a track:
 	res, err := fm.Track.Scrobble(lastfm.ScrobbleParams{
@@ -64,16 +60,12 @@ func main() {
 		Time:        time.Now(),
 	})
 	if err != nil {
-		fmt.Println("Error scrobbling track:", err)
-		return
+		return err
 	}
 
-	s := res.Scrobble
-	fmt.Println("Scrobbled track:", s.Track.Title, "by", s.Artist.Name)
-}
+	sc := res.Scrobble
+	fmt.Println("Scrobbled track:", sc.Track.Title, "by", sc.Artist.Name)
 
-func redirectUserToAuthURL(url string) {
-	// This is a placeholder function. In a real application, you would
-	// redirect the user to the auth URL.
-	fmt.Println("Redirecting user to:", url)
+	http.Redirect(w, r, "/", http.StatusFound)
+	return nil
 }
@@ -22,17 +22,12 @@ func main() {
 
 	err := fm.Login(username, password)
 	if err != nil {
-		var fmerr *api.LastFMError
-		if errors.As(err, &fmerr) {
-			switch fmerr.Code {
-			case api.ErrAuthenticationFailed:
-				fmt.Println("Login failed. Check your username and password.")
-			default:
-				fmt.Println("Login failed:", err)
-				// ...
-			}
-		} else {
-			fmt.Println(err)
+		switch {
+		case errors.Is(err, api.ErrAuthenticationFailedError):
+			fmt.Println("Login failed. Check your username and password.")
+		default:
+			fmt.Println("Login failed:", err)
+			// ...
 		}
 
 		return
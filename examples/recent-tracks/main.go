@@ -23,19 +23,14 @@ func main() {
 
 	res, err := fm.User.RecentTracks(params)
 	if err != nil {
-		var fmerr *api.LastFMError
-		if errors.As(err, &fmerr) {
-			switch fmerr.Code {
-			case api.ErrInvalidParameters:
-				fmt.Println("Invalid parameters")
-			case api.ErrOperationFailed:
-				fmt.Println("Operation failed")
-			default:
-				fmt.Println(err)
-				// ...
-			}
-		} else {
+		switch {
+		case errors.Is(err, api.ErrInvalidParametersError):
+			fmt.Println("Invalid parameters")
+		case errors.Is(err, api.ErrOperationFailedError):
+			fmt.Println("Operation failed")
+		default:
 			fmt.Println(err)
+			// ...
 		}
 
 		return
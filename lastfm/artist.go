@@ -168,23 +168,26 @@ type ArtistTopAlbumsMBIDParams struct {
 }
 
 type ArtistTopAlbums struct {
-	Artist     string `xml:"artist,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Albums     []struct {
-		Title     string `xml:"name"`
-		Playcount int    `xml:"playcount"`
-		URL       string `xml:"url"`
-		MBID      string `xml:"mbid"`
-		Artist    struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Cover Image `xml:"image"`
-	} `xml:"album"`
+	Artist     string           `xml:"artist,attr"`
+	Page       int              `xml:"page,attr"`
+	PerPage    int              `xml:"perPage,attr"`
+	TotalPages int              `xml:"totalPages,attr"`
+	Total      int              `xml:"total,attr"`
+	Albums     []ArtistTopAlbum `xml:"album"`
+}
+
+// ArtistTopAlbum is an album in an artist's top albums.
+type ArtistTopAlbum struct {
+	Title     string `xml:"name"`
+	Playcount int    `xml:"playcount"`
+	URL       string `xml:"url"`
+	MBID      string `xml:"mbid"`
+	Artist    struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Cover Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/artist.getTopTags
@@ -225,26 +228,29 @@ type ArtistTopTracksMBIDParams struct {
 }
 
 type ArtistTopTracks struct {
-	Artist     string `xml:"artist,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title      string  `xml:"name"`
-		Rank       int     `xml:"rank,attr"`
-		Playcount  int     `xml:"playcount"`
-		Listeners  int     `xml:"listeners"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Artist     struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"track"`
+	Artist     string           `xml:"artist,attr"`
+	Page       int              `xml:"page,attr"`
+	PerPage    int              `xml:"perPage,attr"`
+	TotalPages int              `xml:"totalPages,attr"`
+	Total      int              `xml:"total,attr"`
+	Tracks     []ArtistTopTrack `xml:"track"`
+}
+
+// ArtistTopTrack is a track in an artist's top tracks.
+type ArtistTopTrack struct {
+	Title      string  `xml:"name"`
+	Rank       int     `xml:"rank,attr"`
+	Playcount  int     `xml:"playcount"`
+	Listeners  int     `xml:"listeners"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Artist     struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/artist.removeTag
@@ -7,19 +7,22 @@ type ChartTopArtistsParams struct {
 }
 
 type ChartTopArtists struct {
-	Page       int `xml:"page,attr"`
-	PerPage    int `xml:"perPage,attr"`
-	TotalPages int `xml:"totalPages,attr"`
-	Total      int `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		Playcount  int     `xml:"playcount"`
-		Listeners  int     `xml:"listeners"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artist"`
+	Page       int              `xml:"page,attr"`
+	PerPage    int              `xml:"perPage,attr"`
+	TotalPages int              `xml:"totalPages,attr"`
+	Total      int              `xml:"total,attr"`
+	Artists    []ChartTopArtist `xml:"artist"`
+}
+
+// ChartTopArtist is an artist in the top artists chart.
+type ChartTopArtist struct {
+	Name       string  `xml:"name"`
+	Playcount  int     `xml:"playcount"`
+	Listeners  int     `xml:"listeners"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
 
 // https://www.last.fm/api/show/chart.getTopTags
@@ -29,18 +32,21 @@ type ChartTopTagsParams struct {
 }
 
 type ChartTopTags struct {
-	Page       int `xml:"page,attr"`
-	PerPage    int `xml:"perPage,attr"`
-	TotalPages int `xml:"totalPages,attr"`
-	Total      int `xml:"total,attr"`
-	Tags       []struct {
-		Name       string  `xml:"name"`
-		URL        string  `xml:"url"`
-		Reach      int     `xml:"reach"`
-		Count      int     `xml:"taggings"`
-		Streamable IntBool `xml:"streamable"`
-		Wiki       string  `xml:"wiki"`
-	} `xml:"tag"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Tags       []ChartTopTag `xml:"tag"`
+}
+
+// ChartTopTag is a tag in the top tags chart.
+type ChartTopTag struct {
+	Name       string  `xml:"name"`
+	URL        string  `xml:"url"`
+	Reach      int     `xml:"reach"`
+	Count      int     `xml:"taggings"`
+	Streamable IntBool `xml:"streamable"`
+	Wiki       string  `xml:"wiki"`
 }
 
 // https://www.last.fm/api/show/chart.getTopTracks
@@ -50,26 +56,29 @@ type ChartTopTracksParams struct {
 }
 
 type ChartTopTracks struct {
-	Page       int `xml:"page,attr"`
-	PerPage    int `xml:"perPage,attr"`
-	TotalPages int `xml:"totalPages,attr"`
-	Total      int `xml:"total,attr"`
-	Tracks     []struct {
-		Title      string   `xml:"name"`
-		Duration   Duration `xml:"duration"`
-		Playcount  int      `xml:"playcount"`
-		Listeners  int      `xml:"listeners"`
-		URL        string   `xml:"url"`
-		MBID       string   `xml:"mbid"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			Fulltrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"track"`
+	Page       int             `xml:"page,attr"`
+	PerPage    int             `xml:"perPage,attr"`
+	TotalPages int             `xml:"totalPages,attr"`
+	Total      int             `xml:"total,attr"`
+	Tracks     []ChartTopTrack `xml:"track"`
+}
+
+// ChartTopTrack is a track in the top tracks chart.
+type ChartTopTrack struct {
+	Title      string   `xml:"name"`
+	Duration   Duration `xml:"duration"`
+	Playcount  int      `xml:"playcount"`
+	Listeners  int      `xml:"listeners"`
+	URL        string   `xml:"url"`
+	MBID       string   `xml:"mbid"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		Fulltrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
@@ -9,19 +9,22 @@ type GeoTopArtistsParams struct {
 }
 
 type GeoTopArtists struct {
-	Country    string `xml:"country,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		Listeners  int     `xml:"listeners"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artist"`
+	Country    string         `xml:"country,attr"`
+	Page       int            `xml:"page,attr"`
+	PerPage    int            `xml:"perPage,attr"`
+	TotalPages int            `xml:"totalPages,attr"`
+	Total      int            `xml:"total,attr"`
+	Artists    []GeoTopArtist `xml:"artist"`
+}
+
+// GeoTopArtist is an artist in a country's top artists.
+type GeoTopArtist struct {
+	Name       string  `xml:"name"`
+	Listeners  int     `xml:"listeners"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
 
 // https://www.last.fm/api/show/geo.getTopTracks
@@ -36,27 +39,30 @@ type GeoTopTracksParams struct {
 }
 
 type GeoTopTracks struct {
-	Country    string `xml:"country,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title      string   `xml:"name"`
-		Rank       int      `xml:"rank,attr"`
-		Listeners  int      `xml:"listeners"`
-		URL        string   `xml:"url"`
-		MBID       string   `xml:"mbid"`
-		Duration   Duration `xml:"duration"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			FullTrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"track"`
+	Country    string        `xml:"country,attr"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Tracks     []GeoTopTrack `xml:"track"`
+}
+
+// GeoTopTrack is a track in a country's top tracks.
+type GeoTopTrack struct {
+	Title      string   `xml:"name"`
+	Rank       int      `xml:"rank,attr"`
+	Listeners  int      `xml:"listeners"`
+	URL        string   `xml:"url"`
+	MBID       string   `xml:"mbid"`
+	Duration   Duration `xml:"duration"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		FullTrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
@@ -2,7 +2,11 @@
 // Last.fm API.
 package lastfm
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func TestImageURL_Resize(t *testing.T) {
 	cases := []struct {
@@ -74,3 +78,113 @@ func TestImageURL_Resize(t *testing.T) {
 		})
 	}
 }
+
+func TestScrobbleParams_Validate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		params ScrobbleParams
+		played Duration
+		want   ScrobbleReason
+	}{
+		{
+			name:   "Valid",
+			params: ScrobbleParams{Artist: "Artist", Track: "Track", Time: now},
+			played: ScrobbleMaxPlayed,
+			want:   ScrobbleOK,
+		},
+		{
+			name:   "Missing artist",
+			params: ScrobbleParams{Track: "Track", Time: now},
+			want:   ScrobbleMissingArtist,
+		},
+		{
+			name:   "Missing track",
+			params: ScrobbleParams{Artist: "Artist", Time: now},
+			want:   ScrobbleMissingTrack,
+		},
+		{
+			name: "Track too short",
+			params: ScrobbleParams{
+				Artist:   "Artist",
+				Track:    "Track",
+				Time:     now,
+				Duration: 30 * DurationSecond,
+			},
+			want: ScrobbleTrackTooShort,
+		},
+		{
+			name: "Played too short",
+			params: ScrobbleParams{
+				Artist:   "Artist",
+				Track:    "Track",
+				Time:     now,
+				Duration: 3 * DurationMinute,
+			},
+			played: 89 * DurationSecond,
+			want:   ScrobblePlayedTooShort,
+		},
+		{
+			name: "Played half",
+			params: ScrobbleParams{
+				Artist:   "Artist",
+				Track:    "Track",
+				Time:     now,
+				Duration: 3 * DurationMinute,
+			},
+			played: 90 * DurationSecond,
+			want:   ScrobbleOK,
+		},
+		{
+			name: "Played 4 minutes of long track",
+			params: ScrobbleParams{
+				Artist:   "Artist",
+				Track:    "Track",
+				Time:     now,
+				Duration: 20 * DurationMinute,
+			},
+			played: 4 * DurationMinute,
+			want:   ScrobbleOK,
+		},
+		{
+			name:   "Timestamp too old",
+			params: ScrobbleParams{Artist: "Artist", Track: "Track", Time: now.Add(-15 * 24 * time.Hour)},
+			played: ScrobbleMaxPlayed,
+			want:   ScrobbleTimestampTooOld,
+		},
+		{
+			name:   "Timestamp too new",
+			params: ScrobbleParams{Artist: "Artist", Track: "Track", Time: now.Add(time.Minute)},
+			played: ScrobbleMaxPlayed,
+			want:   ScrobbleTimestampTooNew,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.params.ValidateAt(now)
+			if err == nil {
+				err = c.params.ValidatePlayed(c.played)
+			}
+
+			if c.want == ScrobbleOK {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var serr *ScrobbleError
+			if !errors.As(err, &serr) {
+				t.Fatalf("expected *ScrobbleError, got %v", err)
+			}
+			if serr.Reason != c.want {
+				t.Errorf("expected reason %d, got %d", c.want, serr.Reason)
+			}
+			if serr.IgnoredCode() != c.want.IgnoredCode() {
+				t.Errorf("expected ignored code %d, got %d", c.want.IgnoredCode(), serr.IgnoredCode())
+			}
+		})
+	}
+}
@@ -8,18 +8,21 @@ type LibraryArtistsParams struct {
 }
 
 type LibraryArtists struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		Playcount  int     `xml:"playcount"`
-		Tagcount   int     `xml:"tagcount"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artist"`
+	User       string          `xml:"user,attr"`
+	Page       int             `xml:"page,attr"`
+	PerPage    int             `xml:"perPage,attr"`
+	TotalPages int             `xml:"totalPages,attr"`
+	Total      int             `xml:"total,attr"`
+	Artists    []LibraryArtist `xml:"artist"`
+}
+
+// LibraryArtist is an artist in a user's library.
+type LibraryArtist struct {
+	Name       string  `xml:"name"`
+	Playcount  int     `xml:"playcount"`
+	Tagcount   int     `xml:"tagcount"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
@@ -40,23 +40,26 @@ type TagTopAlbumsParams struct {
 }
 
 type TagTopAlbums struct {
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Albums     []struct {
-		Title  string `xml:"name"`
-		Rank   int    `xml:"rank,attr"`
-		URL    string `xml:"url"`
-		MBID   string `xml:"mbid"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Cover Image `xml:"image"`
-	} `xml:"album"`
+	Tag        string        `xml:"tag,attr"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Albums     []TagTopAlbum `xml:"album"`
+}
+
+// TagTopAlbum is an album in a tag's top albums.
+type TagTopAlbum struct {
+	Title  string `xml:"name"`
+	Rank   int    `xml:"rank,attr"`
+	URL    string `xml:"url"`
+	MBID   string `xml:"mbid"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Cover Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/tag.getTopArtists
@@ -67,19 +70,22 @@ type TagTopArtistsParams struct {
 }
 
 type TagTopArtists struct {
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		Rank       int     `xml:"rank,attr"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artist"`
+	Tag        string         `xml:"tag,attr"`
+	Page       int            `xml:"page,attr"`
+	PerPage    int            `xml:"perPage,attr"`
+	TotalPages int            `xml:"totalPages,attr"`
+	Total      int            `xml:"total,attr"`
+	Artists    []TagTopArtist `xml:"artist"`
+}
+
+// TagTopArtist is an artist in a tag's top artists.
+type TagTopArtist struct {
+	Name       string  `xml:"name"`
+	Rank       int     `xml:"rank,attr"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
 
 // https://www.last.fm/api/show/tag.getTopTags
@@ -107,28 +113,31 @@ type TagTopTracksParams struct {
 }
 
 type TagTopTracks struct {
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title      string   `xml:"name"`
-		Rank       int      `xml:"rank,attr"`
-		URL        string   `xml:"url"`
-		MBID       string   `xml:"mbid"`
-		Duration   Duration `xml:"duration"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			FullTrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"track"`
+	Tag        string        `xml:"tag,attr"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Tracks     []TagTopTrack `xml:"track"`
+}
+
+// TagTopTrack is a track in a tag's top tracks.
+type TagTopTrack struct {
+	Title      string   `xml:"name"`
+	Rank       int      `xml:"rank,attr"`
+	URL        string   `xml:"url"`
+	MBID       string   `xml:"mbid"`
+	Duration   Duration `xml:"duration"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		FullTrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/tag.getWeeklyChartList
@@ -236,6 +236,149 @@ type ScrobbleParams struct {
 	StreamID string `url:"streamId,omitempty"`
 }
 
+// Scrobbling rules enforced by Last.fm.
+//
+// https://www.last.fm/api/scrobbling#when-is-a-scrobble-a-scrobble
+const (
+	// ScrobbleMinDuration is the minimum duration of a track that can be
+	// scrobbled.
+	ScrobbleMinDuration = 30 * DurationSecond
+	// ScrobbleMaxPlayed is the listening time after which a track can be
+	// scrobbled, regardless of its duration.
+	ScrobbleMaxPlayed = 4 * DurationMinute
+	// ScrobbleMaxAge is the maximum age of a scrobble's timestamp.
+	ScrobbleMaxAge = 14 * 24 * time.Hour
+)
+
+// ScrobbleReason is the reason a scrobble breaks Last.fm's scrobbling rules.
+type ScrobbleReason int
+
+const (
+	ScrobbleOK ScrobbleReason = iota
+
+	ScrobbleMissingArtist
+	ScrobbleMissingTrack
+	ScrobbleTrackTooShort
+	ScrobblePlayedTooShort
+	ScrobbleTimestampTooOld
+	ScrobbleTimestampTooNew
+)
+
+// Message returns the message for the scrobble reason.
+func (r ScrobbleReason) Message() string {
+	switch r {
+	case ScrobbleOK:
+		return "Scrobble is valid"
+	case ScrobbleMissingArtist:
+		return "Artist is missing"
+	case ScrobbleMissingTrack:
+		return "Track is missing"
+	case ScrobbleTrackTooShort:
+		return "Track is 30 seconds or shorter"
+	case ScrobblePlayedTooShort:
+		return "Track wasn't played for half its duration or 4 minutes"
+	case ScrobbleTimestampTooOld:
+		return "Timestamp is more than 14 days old"
+	case ScrobbleTimestampTooNew:
+		return "Timestamp is in the future"
+	default:
+		return "Scrobble is invalid"
+	}
+}
+
+// IgnoredCode returns the ScrobbleIgnoredCode Last.fm would respond with for
+// a scrobble broken for this reason.
+func (r ScrobbleReason) IgnoredCode() ScrobbleIgnoredCode {
+	switch r {
+	case ScrobbleOK:
+		return ScrobbleNotIgnored
+	case ScrobbleMissingArtist:
+		return ArtistIgnored
+	case ScrobbleTimestampTooOld:
+		return TimestampTooOld
+	case ScrobbleTimestampTooNew:
+		return TimestampTooNew
+	default:
+		return TrackIgnored
+	}
+}
+
+// ScrobbleError is returned when a scrobble breaks Last.fm's scrobbling rules.
+type ScrobbleError struct {
+	Reason ScrobbleReason
+}
+
+// Error implements the error interface.
+func (e *ScrobbleError) Error() string {
+	return "invalid scrobble: " + e.Reason.Message()
+}
+
+// Is checks if the error matches the target error.
+func (e *ScrobbleError) Is(target error) bool {
+	if t, ok := target.(*ScrobbleError); ok {
+		return e.Reason == t.Reason
+	}
+	return false
+}
+
+// IgnoredCode returns the ScrobbleIgnoredCode Last.fm would respond with for
+// the invalid scrobble.
+func (e *ScrobbleError) IgnoredCode() ScrobbleIgnoredCode {
+	return e.Reason.IgnoredCode()
+}
+
+// Validate checks that the scrobble has an artist and track, that the track
+// is longer than ScrobbleMinDuration if its duration is known, and that its
+// timestamp is neither older than ScrobbleMaxAge nor in the future. It returns
+// a *ScrobbleError describing the first rule broken, or nil if the scrobble is
+// valid.
+//
+// Use ValidatePlayed to check whether the track was played for long enough.
+func (p ScrobbleParams) Validate() error {
+	return p.ValidateAt(time.Now())
+}
+
+// ValidateAt is like Validate but checks the timestamp against the given
+// current time.
+func (p ScrobbleParams) ValidateAt(now time.Time) error {
+	switch {
+	case p.Artist == "":
+		return &ScrobbleError{Reason: ScrobbleMissingArtist}
+	case p.Track == "":
+		return &ScrobbleError{Reason: ScrobbleMissingTrack}
+	case p.Duration != 0 && p.Duration <= ScrobbleMinDuration:
+		return &ScrobbleError{Reason: ScrobbleTrackTooShort}
+	case p.Time.Before(now.Add(-ScrobbleMaxAge)):
+		return &ScrobbleError{Reason: ScrobbleTimestampTooOld}
+	case p.Time.After(now):
+		return &ScrobbleError{Reason: ScrobbleTimestampTooNew}
+	}
+
+	return nil
+}
+
+// ValidatePlayed checks that the track was played for long enough to be
+// scrobbled, i.e. for at least PlayThreshold. It returns a *ScrobbleError if
+// it wasn't, or nil if it was.
+func (p ScrobbleParams) ValidatePlayed(played Duration) error {
+	if played < p.PlayThreshold() {
+		return &ScrobbleError{Reason: ScrobblePlayedTooShort}
+	}
+
+	return nil
+}
+
+// PlayThreshold returns how long the track must be played for before it can be
+// scrobbled: half its duration, or ScrobbleMaxPlayed, whichever is shorter. If
+// the duration is unknown, ScrobbleMaxPlayed is returned.
+func (p ScrobbleParams) PlayThreshold() Duration {
+	if p.Duration <= 0 {
+		return ScrobbleMaxPlayed
+	}
+
+	return min(p.Duration/2, ScrobbleMaxPlayed)
+}
+
 // EncodeIndexValues sets the indexed "key[index]" values in v from the fields
 // of p. It returns an error if p cannot be encoded.
 func (p ScrobbleParams) EncodeIndexValues(index int, v *url.Values) error {
@@ -255,6 +398,10 @@ func (p ScrobbleParams) EncodeIndexValues(index int, v *url.Values) error {
 	return nil
 }
 
+// ScrobbleMultiLimit is the maximum number of scrobbles that can be submitted
+// in a single track.scrobble request.
+const ScrobbleMultiLimit = 50
+
 // https://www.last.fm/api/show/track.scrobble
 type ScrobbleMultiParams []ScrobbleParams
 
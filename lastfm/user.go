@@ -13,24 +13,27 @@ type FriendsParams struct {
 }
 
 type Friends struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Users      []struct {
-		Name         string   `xml:"name"`
-		RealName     string   `xml:"realname"`
-		URL          string   `xml:"url"`
-		Country      string   `xml:"country"`
-		Subscriber   IntBool  `xml:"subscriber"`
-		Playcount    int      `xml:"playcount"`
-		Playlists    int      `xml:"playlists"`
-		Bootstrap    int      `xml:"bootstrap"`
-		Avatar       Image    `xml:"image"`
-		RegisteredAt DateTime `xml:"registered"`
-		Type         string   `xml:"type"`
-	} `xml:"user"`
+	User       string   `xml:"user,attr"`
+	Page       int      `xml:"page,attr"`
+	PerPage    int      `xml:"perPage,attr"`
+	TotalPages int      `xml:"totalPages,attr"`
+	Total      int      `xml:"total,attr"`
+	Users      []Friend `xml:"user"`
+}
+
+// Friend is a user in a user's friends list.
+type Friend struct {
+	Name         string   `xml:"name"`
+	RealName     string   `xml:"realname"`
+	URL          string   `xml:"url"`
+	Country      string   `xml:"country"`
+	Subscriber   IntBool  `xml:"subscriber"`
+	Playcount    int      `xml:"playcount"`
+	Playlists    int      `xml:"playlists"`
+	Bootstrap    int      `xml:"bootstrap"`
+	Avatar       Image    `xml:"image"`
+	RegisteredAt DateTime `xml:"registered"`
+	Type         string   `xml:"type"`
 }
 
 // https://www.last.fm/api/show/user.getInfo
@@ -65,27 +68,30 @@ type LovedTracksParams struct {
 }
 
 type LovedTracks struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title  string `xml:"name"`
-		URL    string `xml:"url"`
-		MBID   string `xml:"mbid"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image      Image `xml:"image"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			FullTrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		LovedAt DateTime `xml:"date"`
-	} `xml:"track"`
+	User       string       `xml:"user,attr"`
+	Page       int          `xml:"page,attr"`
+	PerPage    int          `xml:"perPage,attr"`
+	TotalPages int          `xml:"totalPages,attr"`
+	Total      int          `xml:"total,attr"`
+	Tracks     []LovedTrack `xml:"track"`
+}
+
+// LovedTrack is a track loved by a user.
+type LovedTrack struct {
+	Title  string `xml:"name"`
+	URL    string `xml:"url"`
+	MBID   string `xml:"mbid"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image      Image `xml:"image"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		FullTrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	LovedAt DateTime `xml:"date"`
 }
 
 // https://www.last.fm/api/show/user.getPersonalTags
@@ -97,65 +103,74 @@ type UserTagsParams struct {
 }
 
 type UserAlbumTags struct {
-	User       string `xml:"user,attr"`
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Albums     []struct {
-		Title  string `xml:"name"`
-		URL    string `xml:"url"`
-		MBID   string `xml:"mbid"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Cover Image `xml:"image"`
-	} `xml:"albums>album"`
+	User       string        `xml:"user,attr"`
+	Tag        string        `xml:"tag,attr"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Albums     []TaggedAlbum `xml:"albums>album"`
+}
+
+// TaggedAlbum is an album tagged by a user.
+type TaggedAlbum struct {
+	Title  string `xml:"name"`
+	URL    string `xml:"url"`
+	MBID   string `xml:"mbid"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Cover Image `xml:"image"`
 }
 
 type UserArtistTags struct {
-	User       string `xml:"user,attr"`
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artists>artist"`
+	User       string         `xml:"user,attr"`
+	Tag        string         `xml:"tag,attr"`
+	Page       int            `xml:"page,attr"`
+	PerPage    int            `xml:"perPage,attr"`
+	TotalPages int            `xml:"totalPages,attr"`
+	Total      int            `xml:"total,attr"`
+	Artists    []TaggedArtist `xml:"artists>artist"`
+}
+
+// TaggedArtist is an artist tagged by a user.
+type TaggedArtist struct {
+	Name       string  `xml:"name"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
 
 type UserTrackTags struct {
-	User       string `xml:"user,attr"`
-	Tag        string `xml:"tag,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title string `xml:"name"`
-		// All values returned from the Last.fm API are "FIXME". API issue?
-		Duration   string `xml:"duration"`
-		URL        string `xml:"url"`
-		MBID       string `xml:"mbid"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			FullTrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"tracks>track"`
+	User       string        `xml:"user,attr"`
+	Tag        string        `xml:"tag,attr"`
+	Page       int           `xml:"page,attr"`
+	PerPage    int           `xml:"perPage,attr"`
+	TotalPages int           `xml:"totalPages,attr"`
+	Total      int           `xml:"total,attr"`
+	Tracks     []TaggedTrack `xml:"tracks>track"`
+}
+
+// TaggedTrack is a track tagged by a user.
+type TaggedTrack struct {
+	Title string `xml:"name"`
+	// All values returned from the Last.fm API are "FIXME". API issue?
+	Duration   string `xml:"duration"`
+	URL        string `xml:"url"`
+	MBID       string `xml:"mbid"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		FullTrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/user.getRecentTracks
@@ -301,24 +316,27 @@ type UserTopAlbumsParams struct {
 }
 
 type UserTopAlbums struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Albums     []struct {
-		Title     string `xml:"name"`
-		Rank      int    `xml:"rank,attr"`
-		Playcount int    `xml:"playcount"`
-		URL       string `xml:"url"`
-		MBID      string `xml:"mbid"`
-		Artist    struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Cover Image `xml:"image"`
-	} `xml:"album"`
+	User       string         `xml:"user,attr"`
+	Page       int            `xml:"page,attr"`
+	PerPage    int            `xml:"perPage,attr"`
+	TotalPages int            `xml:"totalPages,attr"`
+	Total      int            `xml:"total,attr"`
+	Albums     []UserTopAlbum `xml:"album"`
+}
+
+// UserTopAlbum is an album in a user's top albums.
+type UserTopAlbum struct {
+	Title     string `xml:"name"`
+	Rank      int    `xml:"rank,attr"`
+	Playcount int    `xml:"playcount"`
+	URL       string `xml:"url"`
+	MBID      string `xml:"mbid"`
+	Artist    struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Cover Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/user.getTopArtists
@@ -330,20 +348,23 @@ type UserTopArtistsParams struct {
 }
 
 type UserTopArtists struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Artists    []struct {
-		Name       string  `xml:"name"`
-		Rank       int     `xml:"rank,attr"`
-		Playcount  int     `xml:"playcount"`
-		URL        string  `xml:"url"`
-		MBID       string  `xml:"mbid"`
-		Streamable IntBool `xml:"streamable"`
-		Image      Image   `xml:"image"`
-	} `xml:"artist"`
+	User       string          `xml:"user,attr"`
+	Page       int             `xml:"page,attr"`
+	PerPage    int             `xml:"perPage,attr"`
+	TotalPages int             `xml:"totalPages,attr"`
+	Total      int             `xml:"total,attr"`
+	Artists    []UserTopArtist `xml:"artist"`
+}
+
+// UserTopArtist is an artist in a user's top artists.
+type UserTopArtist struct {
+	Name       string  `xml:"name"`
+	Rank       int     `xml:"rank,attr"`
+	Playcount  int     `xml:"playcount"`
+	URL        string  `xml:"url"`
+	MBID       string  `xml:"mbid"`
+	Streamable IntBool `xml:"streamable"`
+	Image      Image   `xml:"image"`
 }
 
 // https://www.last.fm/api/show/user.getTopTags
@@ -370,29 +391,32 @@ type UserTopTracksParams struct {
 }
 
 type UserTopTracks struct {
-	User       string `xml:"user,attr"`
-	Page       int    `xml:"page,attr"`
-	PerPage    int    `xml:"perPage,attr"`
-	TotalPages int    `xml:"totalPages,attr"`
-	Total      int    `xml:"total,attr"`
-	Tracks     []struct {
-		Title      string   `xml:"name"`
-		Rank       int      `xml:"rank,attr"`
-		Playcount  int      `xml:"playcount"`
-		Duration   Duration `xml:"duration"`
-		URL        string   `xml:"url"`
-		MBID       string   `xml:"mbid"`
-		Streamable struct {
-			Preview   IntBool `xml:",chardata"`
-			FullTrack IntBool `xml:"fulltrack,attr"`
-		} `xml:"streamable"`
-		Artist struct {
-			Name string `xml:"name"`
-			URL  string `xml:"url"`
-			MBID string `xml:"mbid"`
-		} `xml:"artist"`
-		Image Image `xml:"image"`
-	} `xml:"track"`
+	User       string         `xml:"user,attr"`
+	Page       int            `xml:"page,attr"`
+	PerPage    int            `xml:"perPage,attr"`
+	TotalPages int            `xml:"totalPages,attr"`
+	Total      int            `xml:"total,attr"`
+	Tracks     []UserTopTrack `xml:"track"`
+}
+
+// UserTopTrack is a track in a user's top tracks.
+type UserTopTrack struct {
+	Title      string   `xml:"name"`
+	Rank       int      `xml:"rank,attr"`
+	Playcount  int      `xml:"playcount"`
+	Duration   Duration `xml:"duration"`
+	URL        string   `xml:"url"`
+	MBID       string   `xml:"mbid"`
+	Streamable struct {
+		Preview   IntBool `xml:",chardata"`
+		FullTrack IntBool `xml:"fulltrack,attr"`
+	} `xml:"streamable"`
+	Artist struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+		MBID string `xml:"mbid"`
+	} `xml:"artist"`
+	Image Image `xml:"image"`
 }
 
 // https://www.last.fm/api/show/user.getWeeklyAlbumChart
@@ -0,0 +1,1040 @@
+package lastfmtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+)
+
+// sessionMethods are the methods that require a session key.
+var sessionMethods = []api.APIMethod{
+	api.AlbumAddTagsMethod,
+	api.AlbumRemoveTagMethod,
+	api.ArtistAddTagsMethod,
+	api.ArtistRemoveTagMethod,
+	api.TrackAddTagsMethod,
+	api.TrackLoveMethod,
+	api.TrackRemoveTagMethod,
+	api.TrackScrobbleMethod,
+	api.TrackUnloveMethod,
+	api.TrackUpdateNowPlayingMethod,
+}
+
+// methodLevel returns the level of authorisation required by a method.
+func methodLevel(method api.APIMethod) api.RequestLevel {
+	switch {
+	case slices.Contains(sessionMethods, method):
+		return api.RequestLevelSession
+	case strings.HasPrefix(method.String(), "auth."):
+		return api.RequestLevelSecret
+	default:
+		return api.RequestLevelAPIKey
+	}
+}
+
+// emptyRoots are the root elements of the empty responses of methods that
+// aren't backed by the store. The store has no catalog of artists, albums and
+// tracks, and no friends or weekly charts, so these methods are stubbed, and
+// writes to artist and album tags are discarded.
+var emptyRoots = map[api.APIMethod]string{
+	api.AlbumAddTagsMethod:             "",
+	api.AlbumGetInfoMethod:             "album",
+	api.AlbumGetTagsMethod:             "tags",
+	api.AlbumGetTopTagsMethod:          "toptags",
+	api.AlbumRemoveTagMethod:           "",
+	api.AlbumSearchMethod:              "results",
+	api.ArtistAddTagsMethod:            "",
+	api.ArtistGetCorrectionMethod:      "corrections",
+	api.ArtistGetInfoMethod:            "artist",
+	api.ArtistGetSimilarMethod:         "similarartists",
+	api.ArtistGetTagsMethod:            "tags",
+	api.ArtistGetTopAlbumsMethod:       "topalbums",
+	api.ArtistGetTopTagsMethod:         "toptags",
+	api.ArtistGetTopTracksMethod:       "toptracks",
+	api.ArtistRemoveTagMethod:          "",
+	api.ArtistSearchMethod:             "results",
+	api.ChartGetTopArtistsMethod:       "artists",
+	api.ChartGetTopTagsMethod:          "tags",
+	api.ChartGetTopTracksMethod:        "tracks",
+	api.GeoGetTopArtistsMethod:         "topartists",
+	api.GeoGetTopTracksMethod:          "tracks",
+	api.TagGetInfoMethod:               "tag",
+	api.TagGetSimilarMethod:            "similartags",
+	api.TagGetTopAlbumsMethod:          "albums",
+	api.TagGetTopArtistsMethod:         "topartists",
+	api.TagGetTopTagsMethod:            "toptags",
+	api.TagGetTopTracksMethod:          "tracks",
+	api.TagGetWeeklyChartListMethod:    "weeklychartlist",
+	api.TrackGetCorrectionMethod:       "corrections",
+	api.TrackGetInfoMethod:             "track",
+	api.TrackGetSimilarMethod:          "similartracks",
+	api.TrackGetTopTagsMethod:          "toptags",
+	api.TrackSearchMethod:              "results",
+	api.UserGetFriendsMethod:           "friends",
+	api.UserGetPersonalTagsMethod:      "taggings",
+	api.UserGetWeeklyAlbumChartMethod:  "weeklyalbumchart",
+	api.UserGetWeeklyArtistChartMethod: "weeklyartistchart",
+	api.UserGetWeeklyChartListMethod:   "weeklychartlist",
+	api.UserGetWeeklyTrackChartMethod:  "weeklytrackchart",
+}
+
+// builtinHandlers returns the built-in handlers of every method.
+func (s *Server) builtinHandlers() map[api.APIMethod]HandlerFunc {
+	handlers := map[api.APIMethod]HandlerFunc{
+		api.AuthGetTokenMethod:          s.authGetToken,
+		api.AuthGetSessionMethod:        s.authGetSession,
+		api.AuthGetMobileSessionMethod:  s.authGetMobileSession,
+		api.TrackAddTagsMethod:          s.trackAddTags,
+		api.TrackGetTagsMethod:          s.trackGetTags,
+		api.TrackLoveMethod:             s.trackLove,
+		api.TrackRemoveTagMethod:        s.trackRemoveTag,
+		api.TrackScrobbleMethod:         s.trackScrobble,
+		api.TrackUnloveMethod:           s.trackUnlove,
+		api.TrackUpdateNowPlayingMethod: s.trackUpdateNowPlaying,
+		api.UserGetInfoMethod:           s.userGetInfo,
+		api.UserGetLovedTracksMethod:    s.userGetLovedTracks,
+		api.UserGetRecentTracksMethod:   s.userGetRecentTracks,
+		api.UserGetTopAlbumsMethod:      s.userGetTopAlbums,
+		api.UserGetTopArtistsMethod:     s.userGetTopArtists,
+		api.UserGetTopTagsMethod:        s.userGetTopTags,
+		api.UserGetTopTracksMethod:      s.userGetTopTracks,
+		api.LibraryGetArtistsMethod:     s.libraryGetArtists,
+	}
+
+	for method, root := range emptyRoots {
+		handlers[method] = emptyHandler(root)
+	}
+
+	return handlers
+}
+
+func emptyHandler(root string) HandlerFunc {
+	return func(req *Request) (any, error) {
+		if root == "" {
+			return nil, nil
+		}
+		return struct {
+			XMLName xml.Name
+		}{XMLName: xml.Name{Local: root}}, nil
+	}
+}
+
+func invalidParams(message string) error {
+	return api.NewLastFMError(api.ErrInvalidParameters, message)
+}
+
+// requireParams returns an error if any of the given parameters are missing.
+func requireParams(p url.Values, names ...string) error {
+	for _, name := range names {
+		if p.Get(name) == "" {
+			return invalidParams(fmt.Sprintf("Invalid parameters - missing %s", name))
+		}
+	}
+	return nil
+}
+
+// requestUser returns the name of the user a request is for, from the given
+// parameter, or the session user if it's missing.
+func (s *Server) requestUser(req *Request, param string) (string, error) {
+	name := req.Params.Get(param)
+	if name == "" {
+		name = req.User
+	}
+	if name == "" {
+		return "", invalidParams("Invalid parameters - missing " + param)
+	}
+
+	s.mu.Lock()
+	u, ok := s.store.user(name)
+	s.mu.Unlock()
+
+	if !ok {
+		return "", invalidParams("User not found")
+	}
+
+	return u.Name, nil
+}
+
+// page returns the page number and limit of a request, and the start and end
+// indices of the page of a list of n items.
+func page(p url.Values, n int) (page, limit, start, end int) {
+	page, _ = strconv.Atoi(p.Get("page"))
+	page = max(page, 1)
+
+	limit, _ = strconv.Atoi(p.Get("limit"))
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	limit = min(limit, maxLimit)
+
+	start = min((page-1)*limit, n)
+	end = min(start+limit, n)
+
+	return page, limit, start, end
+}
+
+type listAttrs struct {
+	User       string `xml:"user,attr"`
+	Page       int    `xml:"page,attr"`
+	PerPage    int    `xml:"perPage,attr"`
+	TotalPages int    `xml:"totalPages,attr"`
+	Total      int    `xml:"total,attr"`
+}
+
+func newListAttrs(user string, page, limit, total int) listAttrs {
+	return listAttrs{
+		User:       user,
+		Page:       page,
+		PerPage:    limit,
+		TotalPages: (total + limit - 1) / limit,
+		Total:      total,
+	}
+}
+
+type date struct {
+	UTS  int64  `xml:"uts,attr"`
+	Text string `xml:",chardata"`
+}
+
+func newDate(t time.Time) *date {
+	return &date{UTS: t.Unix(), Text: t.UTC().Format(lastfm.TimeFormat)}
+}
+
+type registered struct {
+	Unixtime int64  `xml:"unixtime,attr"`
+	Text     string `xml:",chardata"`
+}
+
+type corrected struct {
+	Corrected int    `xml:"corrected,attr"`
+	Text      string `xml:",chardata"`
+}
+
+type ignoredMessage struct {
+	Code lastfm.ScrobbleIgnoredCode `xml:"code,attr"`
+	Text string                     `xml:",chardata"`
+}
+
+func trackURL(artist, track string) string {
+	return lastfm.BaseURL + "/music/" + url.PathEscape(artist) + "/_/" + url.PathEscape(track)
+}
+
+func artistURL(artist string) string {
+	return lastfm.BaseURL + "/music/" + url.PathEscape(artist)
+}
+
+func (s *Server) authGetToken(req *Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return struct {
+		XMLName xml.Name `xml:"token"`
+		Token   string   `xml:",chardata"`
+	}{Token: s.store.newToken()}, nil
+}
+
+type sessionResponse struct {
+	XMLName    xml.Name `xml:"session"`
+	Name       string   `xml:"name"`
+	Key        string   `xml:"key"`
+	Subscriber int      `xml:"subscriber"`
+}
+
+func (s *Server) authGetSession(req *Request) (any, error) {
+	if err := requireParams(req.Params, "token"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := req.Params.Get("token")
+	user, ok := s.store.tokens[token]
+	if !ok {
+		return nil, api.NewLastFMError(api.ErrAuthenticationFailed, "Invalid authentication token supplied")
+	}
+	if user == "" {
+		return nil, api.NewLastFMError(api.ErrUnauthorizedToken, errorMessage(api.ErrUnauthorizedToken))
+	}
+
+	// tokens can only be used once
+	delete(s.store.tokens, token)
+
+	return s.sessionResponse(user), nil
+}
+
+func (s *Server) authGetMobileSession(req *Request) (any, error) {
+	if err := requireParams(req.Params, "username", "password"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.store.user(req.Params.Get("username"))
+	if !ok || u.Password != req.Params.Get("password") {
+		return nil, api.NewLastFMError(api.ErrAuthenticationFailed,
+			"Authentication Failed - Incorrect username or password")
+	}
+
+	return s.sessionResponse(u.Name), nil
+}
+
+// sessionResponse creates a session for the given user. The caller must hold
+// the lock.
+func (s *Server) sessionResponse(user string) sessionResponse {
+	res := sessionResponse{Name: user, Key: s.store.newSession(user)}
+	if u, ok := s.store.user(user); ok && u.Subscriber {
+		res.Subscriber = 1
+	}
+	return res
+}
+
+func (s *Server) trackAddTags(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track", "tags"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := strings.ToLower(req.User)
+	if s.store.tags[user] == nil {
+		s.store.tags[user] = make(map[trackKey][]string)
+	}
+
+	key := newTrackKey(req.Params.Get("artist"), req.Params.Get("track"))
+	tags := s.store.tags[user][key]
+	for _, tag := range strings.Split(req.Params.Get("tags"), ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	s.store.tags[user][key] = tags
+
+	return nil, nil
+}
+
+func (s *Server) trackRemoveTag(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track", "tag"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := strings.ToLower(req.User)
+	key := newTrackKey(req.Params.Get("artist"), req.Params.Get("track"))
+	tag := strings.ToLower(req.Params.Get("tag"))
+
+	if tags, ok := s.store.tags[user][key]; ok {
+		s.store.tags[user][key] = slices.DeleteFunc(tags, func(t string) bool {
+			return t == tag
+		})
+	}
+
+	return nil, nil
+}
+
+func (s *Server) trackGetTags(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track"); err != nil {
+		return nil, err
+	}
+
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	artist, track := req.Params.Get("artist"), req.Params.Get("track")
+
+	type tag struct {
+		Name string `xml:"name"`
+		URL  string `xml:"url"`
+	}
+	res := struct {
+		XMLName xml.Name `xml:"tags"`
+		Artist  string   `xml:"artist,attr"`
+		Track   string   `xml:"track,attr"`
+		Tags    []tag    `xml:"tag"`
+	}{Artist: artist, Track: track}
+
+	for _, name := range s.TrackTags(user, artist, track) {
+		res.Tags = append(res.Tags, tag{
+			Name: name,
+			URL:  lastfm.BaseURL + "/tag/" + url.PathEscape(name),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) trackLove(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := strings.ToLower(req.User)
+	artist, track := req.Params.Get("artist"), req.Params.Get("track")
+	key := newTrackKey(artist, track)
+
+	loved := slices.DeleteFunc(s.store.loved[user], func(t LovedTrack) bool {
+		return newTrackKey(t.Artist, t.Track) == key
+	})
+	s.store.loved[user] = slices.Insert(loved, 0, LovedTrack{
+		Artist:  artist,
+		Track:   track,
+		LovedAt: s.Now(),
+	})
+
+	return nil, nil
+}
+
+func (s *Server) trackUnlove(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track"); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := strings.ToLower(req.User)
+	key := newTrackKey(req.Params.Get("artist"), req.Params.Get("track"))
+
+	s.store.loved[user] = slices.DeleteFunc(s.store.loved[user], func(t LovedTrack) bool {
+		return newTrackKey(t.Artist, t.Track) == key
+	})
+
+	return nil, nil
+}
+
+type scrobbleResponse struct {
+	Track          corrected      `xml:"track"`
+	Artist         corrected      `xml:"artist"`
+	Album          corrected      `xml:"album"`
+	AlbumArtist    corrected      `xml:"albumArtist"`
+	Timestamp      int64          `xml:"timestamp"`
+	IgnoredMessage ignoredMessage `xml:"ignoredMessage"`
+}
+
+// scrobbleParams parses the scrobbles of a track.scrobble request, which are
+// either given as plain parameters, or indexed "key[index]" parameters.
+func scrobbleParams(p url.Values) ([]lastfm.ScrobbleParams, error) {
+	get := func(key string, i int) string {
+		if i < 0 {
+			return p.Get(key)
+		}
+		return p.Get(fmt.Sprintf("%s[%d]", key, i))
+	}
+
+	indices := []int{-1}
+	if p.Has("artist[0]") {
+		indices = nil
+		for i := 0; p.Has(fmt.Sprintf("artist[%d]", i)); i++ {
+			indices = append(indices, i)
+		}
+	}
+
+	if len(indices) > lastfm.ScrobbleMultiLimit {
+		return nil, invalidParams("Invalid parameters - too many scrobbles")
+	}
+
+	scrobbles := make([]lastfm.ScrobbleParams, len(indices))
+	for n, i := range indices {
+		ts, err := strconv.ParseInt(get("timestamp", i), 10, 64)
+		if err != nil {
+			return nil, invalidParams("Invalid parameters - invalid timestamp")
+		}
+
+		number, _ := strconv.Atoi(get("trackNumber", i))
+		duration, _ := strconv.Atoi(get("duration", i))
+
+		scrobbles[n] = lastfm.ScrobbleParams{
+			Artist:      get("artist", i),
+			Track:       get("track", i),
+			Time:        time.Unix(ts, 0),
+			Album:       get("album", i),
+			AlbumArtist: get("albumArtist", i),
+			TrackNumber: number,
+			Duration:    lastfm.DurationSeconds(duration),
+			MBID:        get("mbid", i),
+			Context:     get("context", i),
+			StreamID:    get("streamId", i),
+		}
+	}
+
+	return scrobbles, nil
+}
+
+func (s *Server) trackScrobble(req *Request) (any, error) {
+	scrobbles, err := scrobbleParams(req.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	res := struct {
+		XMLName   xml.Name           `xml:"scrobbles"`
+		Accepted  int                `xml:"accepted,attr"`
+		Ignored   int                `xml:"ignored,attr"`
+		Scrobbles []scrobbleResponse `xml:"scrobble"`
+	}{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	for _, sc := range scrobbles {
+		item := scrobbleResponse{
+			Track:       corrected{Text: sc.Track},
+			Artist:      corrected{Text: sc.Artist},
+			Album:       corrected{Text: sc.Album},
+			AlbumArtist: corrected{Text: sc.AlbumArtist},
+			Timestamp:   sc.Time.Unix(),
+		}
+
+		if err := sc.ValidateAt(now); err != nil {
+			serr := err.(*lastfm.ScrobbleError)
+			item.IgnoredMessage.Code = serr.IgnoredCode()
+			item.IgnoredMessage.Text = serr.Reason.Message()
+			res.Ignored++
+		} else {
+			s.store.scrobbles = append(s.store.scrobbles, Scrobble{User: req.User, Params: sc})
+			res.Accepted++
+		}
+
+		res.Scrobbles = append(res.Scrobbles, item)
+	}
+
+	return res, nil
+}
+
+func (s *Server) trackUpdateNowPlaying(req *Request) (any, error) {
+	if err := requireParams(req.Params, "artist", "track"); err != nil {
+		return nil, err
+	}
+
+	number, _ := strconv.Atoi(req.Params.Get("trackNumber"))
+	duration, _ := strconv.Atoi(req.Params.Get("duration"))
+
+	np := lastfm.UpdateNowPlayingParams{
+		Artist:      req.Params.Get("artist"),
+		Track:       req.Params.Get("track"),
+		Album:       req.Params.Get("album"),
+		AlbumArtist: req.Params.Get("albumArtist"),
+		TrackNumber: number,
+		Duration:    lastfm.DurationSeconds(duration),
+		MBID:        req.Params.Get("mbid"),
+		Context:     req.Params.Get("context"),
+	}
+
+	s.mu.Lock()
+	s.store.nowPlaying[strings.ToLower(req.User)] = np
+	s.mu.Unlock()
+
+	return struct {
+		XMLName        xml.Name       `xml:"nowplaying"`
+		Track          corrected      `xml:"track"`
+		Artist         corrected      `xml:"artist"`
+		Album          corrected      `xml:"album"`
+		AlbumArtist    corrected      `xml:"albumArtist"`
+		IgnoredMessage ignoredMessage `xml:"ignoredMessage"`
+	}{
+		Track:       corrected{Text: np.Track},
+		Artist:      corrected{Text: np.Artist},
+		Album:       corrected{Text: np.Album},
+		AlbumArtist: corrected{Text: np.AlbumArtist},
+	}, nil
+}
+
+func (s *Server) userGetInfo(req *Request) (any, error) {
+	name, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	u := *s.store.users[strings.ToLower(name)]
+	s.mu.Unlock()
+
+	scrobbles := s.Scrobbles(name)
+
+	artists := make(map[string]bool)
+	albums := make(map[string]bool)
+	tracks := make(map[trackKey]bool)
+	for _, sc := range scrobbles {
+		artists[strings.ToLower(sc.Artist)] = true
+		if sc.Album != "" {
+			albums[strings.ToLower(sc.Artist+"\x00"+sc.Album)] = true
+		}
+		tracks[newTrackKey(sc.Artist, sc.Track)] = true
+	}
+
+	subscriber := 0
+	if u.Subscriber {
+		subscriber = 1
+	}
+
+	return struct {
+		XMLName     xml.Name   `xml:"user"`
+		Name        string     `xml:"name"`
+		RealName    string     `xml:"realname"`
+		URL         string     `xml:"url"`
+		Country     string     `xml:"country"`
+		Subscriber  int        `xml:"subscriber"`
+		Playcount   int        `xml:"playcount"`
+		Registered  registered `xml:"registered"`
+		Type        string     `xml:"type"`
+		ArtistCount int        `xml:"artist_count"`
+		AlbumCount  int        `xml:"album_count"`
+		TrackCount  int        `xml:"track_count"`
+	}{
+		Name:        u.Name,
+		RealName:    u.RealName,
+		URL:         lastfm.BaseURL + "/user/" + url.PathEscape(u.Name),
+		Country:     u.Country,
+		Subscriber:  subscriber,
+		Playcount:   len(scrobbles),
+		Type:        "user",
+		ArtistCount: len(artists),
+		AlbumCount:  len(albums),
+		TrackCount:  len(tracks),
+		Registered: registered{
+			Unixtime: u.RegisteredAt.Unix(),
+			Text:     strconv.FormatInt(u.RegisteredAt.Unix(), 10),
+		},
+	}, nil
+}
+
+type lovedTrackResponse struct {
+	Name   string `xml:"name"`
+	MBID   string `xml:"mbid"`
+	URL    string `xml:"url"`
+	Date   *date  `xml:"date"`
+	Artist struct {
+		Name string `xml:"name"`
+		MBID string `xml:"mbid"`
+		URL  string `xml:"url"`
+	} `xml:"artist"`
+	Streamable corrected `xml:"streamable"`
+}
+
+func (s *Server) userGetLovedTracks(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	loved := s.LovedTracks(user)
+	page, limit, start, end := page(req.Params, len(loved))
+
+	res := struct {
+		XMLName xml.Name `xml:"lovedtracks"`
+		listAttrs
+		Tracks []lovedTrackResponse `xml:"track"`
+	}{listAttrs: newListAttrs(user, page, limit, len(loved))}
+
+	for _, t := range loved[start:end] {
+		item := lovedTrackResponse{
+			Name: t.Track,
+			URL:  trackURL(t.Artist, t.Track),
+			Date: newDate(t.LovedAt),
+		}
+		item.Artist.Name = t.Artist
+		item.Artist.URL = artistURL(t.Artist)
+		item.Streamable.Text = "0"
+		res.Tracks = append(res.Tracks, item)
+	}
+
+	return res, nil
+}
+
+type mbidText struct {
+	MBID string `xml:"mbid,attr"`
+	Text string `xml:",chardata"`
+}
+
+type recentTrackResponse struct {
+	NowPlaying string   `xml:"nowplaying,attr,omitempty"`
+	Artist     any      `xml:"artist"`
+	Name       string   `xml:"name"`
+	Streamable int      `xml:"streamable"`
+	MBID       string   `xml:"mbid"`
+	Album      mbidText `xml:"album"`
+	URL        string   `xml:"url"`
+	Date       *date    `xml:"date,omitempty"`
+	Loved      *int     `xml:"loved,omitempty"`
+}
+
+type extendedArtist struct {
+	Name string `xml:"name"`
+	MBID string `xml:"mbid"`
+	URL  string `xml:"url"`
+}
+
+func (s *Server) userGetRecentTracks(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	var from, to int64
+	if v := req.Params.Get("from"); v != "" {
+		from, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v := req.Params.Get("to"); v != "" {
+		to, _ = strconv.ParseInt(v, 10, 64)
+	}
+	extended := req.Params.Get("extended") == "1"
+
+	var scrobbles []lastfm.ScrobbleParams
+	for _, sc := range s.Scrobbles(user) {
+		ts := sc.Time.Unix()
+		if (from == 0 || ts >= from) && (to == 0 || ts <= to) {
+			scrobbles = append(scrobbles, sc)
+		}
+	}
+	slices.Reverse(scrobbles)
+
+	page, limit, start, end := page(req.Params, len(scrobbles))
+
+	res := struct {
+		XMLName xml.Name `xml:"recenttracks"`
+		listAttrs
+		Tracks []recentTrackResponse `xml:"track"`
+	}{listAttrs: newListAttrs(user, page, limit, len(scrobbles))}
+
+	loved := make(map[trackKey]bool)
+	for _, t := range s.LovedTracks(user) {
+		loved[newTrackKey(t.Artist, t.Track)] = true
+	}
+
+	track := func(artist, name, album, mbid string) recentTrackResponse {
+		t := recentTrackResponse{
+			Name:  name,
+			MBID:  mbid,
+			Album: mbidText{Text: album},
+			URL:   trackURL(artist, name),
+		}
+		if extended {
+			t.Artist = extendedArtist{Name: artist, URL: artistURL(artist)}
+			l := 0
+			if loved[newTrackKey(artist, name)] {
+				l = 1
+			}
+			t.Loved = &l
+		} else {
+			t.Artist = mbidText{Text: artist}
+		}
+		return t
+	}
+
+	if np, ok := s.NowPlaying(user); ok {
+		t := track(np.Artist, np.Track, np.Album, np.MBID)
+		t.NowPlaying = "true"
+		res.Tracks = append(res.Tracks, t)
+	}
+
+	for _, sc := range scrobbles[start:end] {
+		t := track(sc.Artist, sc.Track, sc.Album, sc.MBID)
+		t.Date = newDate(sc.Time)
+		res.Tracks = append(res.Tracks, t)
+	}
+
+	return res, nil
+}
+
+// chartEntry is an artist, album or track in a chart of a user's scrobbles.
+type chartEntry struct {
+	artist    string
+	album     string
+	track     string
+	playcount int
+}
+
+// periodStart returns the start of the given period ending at now, or the
+// zero time for the overall period and unknown periods.
+func periodStart(period lastfm.Period, now time.Time) time.Time {
+	switch period {
+	case lastfm.PeriodWeek:
+		return now.AddDate(0, 0, -7)
+	case lastfm.PeriodMonth:
+		return now.AddDate(0, -1, 0)
+	case lastfm.Period3Months:
+		return now.AddDate(0, -3, 0)
+	case lastfm.Period6Months:
+		return now.AddDate(0, -6, 0)
+	case lastfm.PeriodYear:
+		return now.AddDate(-1, 0, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+// chart counts the scrobbles of the given user in the period of a request,
+// grouped into entries by entry, which reports false for scrobbles to skip.
+// Entries are sorted by playcount, most played first.
+func (s *Server) chart(
+	req *Request, user string, entry func(lastfm.ScrobbleParams) (chartEntry, bool)) []chartEntry {
+
+	since := periodStart(lastfm.Period(req.Params.Get("period")), s.Now())
+
+	var entries []chartEntry
+	index := make(map[chartEntry]int)
+	for _, sc := range s.Scrobbles(user) {
+		if sc.Time.Before(since) {
+			continue
+		}
+
+		e, ok := entry(sc)
+		if !ok {
+			continue
+		}
+
+		key := chartEntry{
+			artist: strings.ToLower(e.artist),
+			album:  strings.ToLower(e.album),
+			track:  strings.ToLower(e.track),
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(entries)
+			index[key] = i
+			entries = append(entries, e)
+		}
+		entries[i].playcount++
+	}
+
+	slices.SortStableFunc(entries, func(a, b chartEntry) int {
+		return b.playcount - a.playcount
+	})
+
+	return entries
+}
+
+type chartArtist struct {
+	Name string `xml:"name"`
+	URL  string `xml:"url"`
+	MBID string `xml:"mbid"`
+}
+
+func newChartArtist(name string) chartArtist {
+	return chartArtist{Name: name, URL: artistURL(name)}
+}
+
+func (s *Server) userGetTopArtists(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.chart(req, user, func(sc lastfm.ScrobbleParams) (chartEntry, bool) {
+		return chartEntry{artist: sc.Artist}, true
+	})
+	page, limit, start, end := page(req.Params, len(entries))
+
+	type artist struct {
+		Rank       int    `xml:"rank,attr"`
+		Name       string `xml:"name"`
+		Playcount  int    `xml:"playcount"`
+		URL        string `xml:"url"`
+		MBID       string `xml:"mbid"`
+		Streamable int    `xml:"streamable"`
+	}
+	res := struct {
+		XMLName xml.Name `xml:"topartists"`
+		listAttrs
+		Artists []artist `xml:"artist"`
+	}{listAttrs: newListAttrs(user, page, limit, len(entries))}
+
+	for i, e := range entries[start:end] {
+		res.Artists = append(res.Artists, artist{
+			Rank:      start + i + 1,
+			Name:      e.artist,
+			Playcount: e.playcount,
+			URL:       artistURL(e.artist),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) userGetTopAlbums(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.chart(req, user, func(sc lastfm.ScrobbleParams) (chartEntry, bool) {
+		artist := sc.AlbumArtist
+		if artist == "" {
+			artist = sc.Artist
+		}
+		return chartEntry{artist: artist, album: sc.Album}, sc.Album != ""
+	})
+	page, limit, start, end := page(req.Params, len(entries))
+
+	type album struct {
+		Rank      int         `xml:"rank,attr"`
+		Name      string      `xml:"name"`
+		Playcount int         `xml:"playcount"`
+		URL       string      `xml:"url"`
+		MBID      string      `xml:"mbid"`
+		Artist    chartArtist `xml:"artist"`
+	}
+	res := struct {
+		XMLName xml.Name `xml:"topalbums"`
+		listAttrs
+		Albums []album `xml:"album"`
+	}{listAttrs: newListAttrs(user, page, limit, len(entries))}
+
+	for i, e := range entries[start:end] {
+		res.Albums = append(res.Albums, album{
+			Rank:      start + i + 1,
+			Name:      e.album,
+			Playcount: e.playcount,
+			URL:       artistURL(e.artist) + "/" + url.PathEscape(e.album),
+			Artist:    newChartArtist(e.artist),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) userGetTopTracks(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.chart(req, user, func(sc lastfm.ScrobbleParams) (chartEntry, bool) {
+		return chartEntry{artist: sc.Artist, track: sc.Track}, true
+	})
+	page, limit, start, end := page(req.Params, len(entries))
+
+	type track struct {
+		Rank       int         `xml:"rank,attr"`
+		Name       string      `xml:"name"`
+		Playcount  int         `xml:"playcount"`
+		URL        string      `xml:"url"`
+		MBID       string      `xml:"mbid"`
+		Streamable corrected   `xml:"streamable"`
+		Artist     chartArtist `xml:"artist"`
+	}
+	res := struct {
+		XMLName xml.Name `xml:"toptracks"`
+		listAttrs
+		Tracks []track `xml:"track"`
+	}{listAttrs: newListAttrs(user, page, limit, len(entries))}
+
+	for i, e := range entries[start:end] {
+		res.Tracks = append(res.Tracks, track{
+			Rank:       start + i + 1,
+			Name:       e.track,
+			Playcount:  e.playcount,
+			URL:        trackURL(e.artist, e.track),
+			Streamable: corrected{Text: "0"},
+			Artist:     newChartArtist(e.artist),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *Server) userGetTopTags(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	type tag struct {
+		Name  string `xml:"name"`
+		Count int    `xml:"count"`
+		URL   string `xml:"url"`
+	}
+
+	s.mu.Lock()
+	var tags []tag
+	index := make(map[string]int)
+	for _, names := range s.store.tags[strings.ToLower(user)] {
+		for _, name := range names {
+			i, ok := index[name]
+			if !ok {
+				i = len(tags)
+				index[name] = i
+				tags = append(tags, tag{
+					Name: name,
+					URL:  lastfm.BaseURL + "/tag/" + url.PathEscape(name),
+				})
+			}
+			tags[i].Count++
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(tags, func(a, b tag) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	_, _, _, end := page(req.Params, len(tags))
+
+	return struct {
+		XMLName xml.Name `xml:"toptags"`
+		User    string   `xml:"user,attr"`
+		Tags    []tag    `xml:"tag"`
+	}{User: user, Tags: tags[:end]}, nil
+}
+
+func (s *Server) libraryGetArtists(req *Request) (any, error) {
+	user, err := s.requestUser(req, "user")
+	if err != nil {
+		return nil, err
+	}
+
+	entries := s.chart(req, user, func(sc lastfm.ScrobbleParams) (chartEntry, bool) {
+		return chartEntry{artist: sc.Artist}, true
+	})
+	page, limit, start, end := page(req.Params, len(entries))
+
+	type artist struct {
+		Name       string `xml:"name"`
+		Playcount  int    `xml:"playcount"`
+		Tagcount   int    `xml:"tagcount"`
+		URL        string `xml:"url"`
+		MBID       string `xml:"mbid"`
+		Streamable int    `xml:"streamable"`
+	}
+	res := struct {
+		XMLName xml.Name `xml:"artists"`
+		listAttrs
+		Artists []artist `xml:"artist"`
+	}{listAttrs: newListAttrs(user, page, limit, len(entries))}
+
+	for _, e := range entries[start:end] {
+		res.Artists = append(res.Artists, artist{
+			Name:      e.artist,
+			Playcount: e.playcount,
+			URL:       artistURL(e.artist),
+		})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,99 @@
+package lastfmtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// xmlNode is an element of an XML response, converted to JSON by xmlToJSON.
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	text     strings.Builder
+	children []*xmlNode
+}
+
+// xmlToJSON converts the XML of a successful response, without the lfm root
+// element, into the equivalent Last.fm JSON response, as api.FormatJSON
+// clients expect.
+//
+// Last.fm generates its JSON responses from its XML responses: elements with
+// only character data become strings, elements with character data and
+// attributes become objects with a "#text" member and a member for each
+// attribute, and other elements become objects with their attributes in an
+// "@attr" member. Repeated elements become arrays.
+func xmlToJSON(inner []byte) ([]byte, error) {
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+
+	dec := xml.NewDecoder(strings.NewReader(string(inner)))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: tok.Name.Local, attrs: tok.Attr}
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			parent.text.Write(tok)
+		}
+	}
+
+	return json.Marshal(jsonChildren(root))
+}
+
+// jsonValue returns the JSON value of the given element.
+func jsonValue(n *xmlNode) any {
+	if len(n.children) == 0 {
+		if len(n.attrs) == 0 {
+			return n.text.String()
+		}
+
+		obj := map[string]any{"#text": n.text.String()}
+		for _, a := range n.attrs {
+			obj[a.Name.Local] = a.Value
+		}
+		return obj
+	}
+
+	obj := jsonChildren(n)
+	if len(n.attrs) > 0 {
+		attrs := make(map[string]any, len(n.attrs))
+		for _, a := range n.attrs {
+			attrs[a.Name.Local] = a.Value
+		}
+		obj["@attr"] = attrs
+	}
+	return obj
+}
+
+// jsonChildren returns the JSON members of the child elements of the given
+// element, with repeated elements as arrays.
+func jsonChildren(n *xmlNode) map[string]any {
+	obj := make(map[string]any)
+	for _, c := range n.children {
+		v := jsonValue(c)
+		switch prev := obj[c.name].(type) {
+		case nil:
+			obj[c.name] = v
+		case []any:
+			obj[c.name] = append(prev, v)
+		default:
+			obj[c.name] = []any{prev, v}
+		}
+	}
+	return obj
+}
@@ -0,0 +1,423 @@
+// Package lastfmtest provides a fake Last.fm API server for testing code that
+// uses the api and session packages, without making requests to Last.fm.
+//
+// The Server runs in-process on an httptest.Server and keeps users, tokens,
+// session keys, scrobbles, now playing tracks, loved tracks and tags in memory.
+// It checks API keys, validates request signatures using api.Signature, and
+// issues tokens and session keys for the web, desktop and mobile auth flows.
+// Errors and HTTP status codes can be injected per method with SetFault.
+// Responses are in XML, or in JSON for requests with the format=json
+// parameter, such as those of clients using api.FormatJSON.
+//
+// The top artists, albums, tracks and tags of users, and their library, are
+// computed from the store. The store has no catalog, so methods about
+// artists, albums, tracks and tags themselves, and the chart, geo, friends
+// and weekly chart methods, aren't backed by it, and respond with an empty,
+// successful response. Use Handle to respond to them with custom data.
+//
+// Recorder records real request and response pairs to a cassette file, with
+// API keys, session keys, signatures and passwords scrubbed, and replays them
+// offline, for testing against real Last.fm responses.
+//
+// Usage:
+//   - Create a Server with NewServer and defer its Close method.
+//   - Add users with AddUser.
+//   - Point clients at the server with Client, SessionClient or Service.
+//   - Inspect the store with methods such as Scrobbles and NowPlaying.
+//   - Record and replay interactions by setting a Recorder as the Client of an
+//     api.API.
+package lastfmtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/session"
+)
+
+const (
+	DefaultAPIKey = "lastfmtest-api-key"
+	DefaultSecret = "lastfmtest-secret"
+)
+
+// Fault is an error injected into the responses of a method.
+type Fault struct {
+	// Code is the Last.fm error code to respond with. If NoError, only the
+	// status code is set, and the response body is empty.
+	Code api.ErrorCode
+	// Message is the error message to respond with. Defaults to a generic
+	// message for the code.
+	Message string
+	// StatusCode is the HTTP status code to respond with. Defaults to 200 OK
+	// if Code is set, or 500 Internal Server Error otherwise.
+	StatusCode int
+	// Times is the number of requests the fault applies to, after which it is
+	// removed. Zero means the fault applies until it is cleared.
+	Times int
+}
+
+// HandlerFunc responds to requests for a method. It returns the value to
+// marshal as the inner XML of a successful response, or an error. Returning a
+// *api.LastFMError responds with that error code.
+type HandlerFunc func(req *Request) (any, error)
+
+// Request is a request to the fake server.
+type Request struct {
+	// Method is the API method requested.
+	Method api.APIMethod
+	// Params are the parameters of the request, from the URL query of GET
+	// requests, or the body of POST requests.
+	Params url.Values
+	// User is the name of the user authenticated by the session key of the
+	// request, if any.
+	User string
+}
+
+// Server is a fake Last.fm API server.
+type Server struct {
+	*httptest.Server
+	// APIKey is the API key the server accepts.
+	APIKey string
+	// Secret is the API secret the server validates signatures with.
+	Secret string
+	// Now returns the current time of the server. Defaults to time.Now.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	store    store
+	faults   map[api.APIMethod]*Fault
+	handlers map[api.APIMethod]HandlerFunc
+	builtin  map[api.APIMethod]HandlerFunc
+}
+
+// NewServer starts and returns a new Server that accepts the given API key and
+// secret. If empty, DefaultAPIKey and DefaultSecret are used. The caller should
+// call Close when finished, to shut it down.
+func NewServer(apiKey, secret string) *Server {
+	if apiKey == "" {
+		apiKey = DefaultAPIKey
+	}
+	if secret == "" {
+		secret = DefaultSecret
+	}
+
+	s := &Server{
+		APIKey:   apiKey,
+		Secret:   secret,
+		Now:      time.Now,
+		store:    newStore(),
+		faults:   make(map[api.APIMethod]*Fault),
+		handlers: make(map[api.APIMethod]HandlerFunc),
+	}
+	s.builtin = s.builtinHandlers()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/2.0/", s.serveAPI)
+	mux.HandleFunc("/api/auth/", s.serveAuth)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Service returns the Service of the server, to be used with
+// api.API.SetService.
+func (s *Server) Service() api.Service {
+	return api.Service{
+		Name:         "lastfmtest",
+		Endpoint:     s.URL + "/2.0/",
+		AuthEndpoint: s.URL + "/api/auth/",
+	}
+}
+
+// Client returns a new api.Client that sends requests to the server.
+func (s *Server) Client() *api.Client {
+	c := api.NewClient(s.APIKey, s.Secret)
+	s.configure(c.API)
+	return c
+}
+
+// SessionClient returns a new session.Client that sends requests to the
+// server, authenticated as the given user. The user is added if it doesn't
+// exist yet.
+func (s *Server) SessionClient(user string) *session.Client {
+	c := session.NewClient(s.APIKey, s.Secret)
+	s.configure(c.API)
+	c.SetSessionKey(s.SessionKey(user))
+	return c
+}
+
+func (s *Server) configure(a *api.API) {
+	a.SetService(s.Service())
+	a.Client = s.Server.Client()
+	a.SetRetryPolicy(nil)
+}
+
+// SetFault injects the given fault into the responses of the given method.
+func (s *Server) SetFault(method api.APIMethod, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[method] = &fault
+}
+
+// ClearFault removes the fault injected into the responses of the given
+// method.
+func (s *Server) ClearFault(method api.APIMethod) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.faults, method)
+}
+
+// Handle sets the handler that responds to requests for the given method,
+// replacing the built-in handler. Credentials, signatures and faults are still
+// checked before the handler is called.
+func (s *Server) Handle(method api.APIMethod, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[method] = handler
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, api.FormatXML, http.StatusBadRequest, api.ErrInvalidParameters, "")
+		return
+	}
+
+	params := r.Form
+	method := api.APIMethod(params.Get("method"))
+	format := api.Format(params.Get("format"))
+
+	if fault, ok := s.takeFault(method); ok {
+		if fault.Code == api.NoError {
+			w.WriteHeader(fault.StatusCode)
+			return
+		}
+		writeError(w, format, fault.StatusCode, fault.Code, fault.Message)
+		return
+	}
+
+	req, lferr := s.authenticate(method, params)
+	if lferr != nil {
+		writeError(w, format, http.StatusOK, lferr.Code, lferr.Message)
+		return
+	}
+
+	s.mu.Lock()
+	handler, ok := s.handlers[method]
+	s.mu.Unlock()
+	if !ok {
+		handler, ok = s.builtin[method]
+	}
+	if !ok {
+		writeError(w, format, http.StatusOK, api.ErrInvalidMethod, "")
+		return
+	}
+
+	res, err := handler(req)
+	if err != nil {
+		code, message := api.ErrOperationFailed, err.Error()
+		if lferr, ok := err.(*api.LastFMError); ok {
+			code, message = lferr.Code, lferr.Message
+		}
+		writeError(w, format, http.StatusOK, code, message)
+		return
+	}
+
+	writeOK(w, format, res)
+}
+
+// serveAuth serves the page users are sent to in order to authorize a token.
+// The fake server can't log users in, so tokens must be authorized with
+// AuthorizeToken instead.
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("Authorize tokens with lastfmtest.Server.AuthorizeToken.\n"))
+}
+
+// takeFault returns the fault injected into the given method, if any, and
+// counts it towards its number of times.
+func (s *Server) takeFault(method api.APIMethod) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fault, ok := s.faults[method]
+	if !ok {
+		return Fault{}, false
+	}
+
+	if fault.Times > 0 {
+		fault.Times--
+		if fault.Times == 0 {
+			delete(s.faults, method)
+		}
+	}
+
+	f := *fault
+	if f.StatusCode == 0 {
+		f.StatusCode = http.StatusOK
+		if f.Code == api.NoError {
+			f.StatusCode = http.StatusInternalServerError
+		}
+	}
+
+	return f, true
+}
+
+// authenticate checks the API key, signature and session key of a request as
+// required by the method.
+func (s *Server) authenticate(
+	method api.APIMethod, params url.Values) (*Request, *api.LastFMError) {
+
+	if params.Get("api_key") != s.APIKey {
+		return nil, api.NewLastFMError(api.ErrInvalidAPIKey, errorMessage(api.ErrInvalidAPIKey))
+	}
+
+	level := methodLevel(method)
+	sig := params.Get("api_sig")
+
+	if sig != "" || level >= api.RequestLevelSecret {
+		p := url.Values{}
+		for k, v := range params {
+			if k != "api_sig" {
+				p[k] = v
+			}
+		}
+
+		if sig != api.Signature(p, s.Secret) {
+			return nil, api.NewLastFMError(
+				api.ErrInvalidMethodSignature, errorMessage(api.ErrInvalidMethodSignature))
+		}
+	}
+
+	req := &Request{Method: method, Params: params}
+
+	if sk := params.Get("sk"); sk != "" || level == api.RequestLevelSession {
+		s.mu.Lock()
+		user, ok := s.store.sessions[sk]
+		s.mu.Unlock()
+
+		if !ok {
+			return nil, api.NewLastFMError(
+				api.ErrInvalidSessionKey, errorMessage(api.ErrInvalidSessionKey))
+		}
+		req.User = user
+	}
+
+	return req, nil
+}
+
+type response struct {
+	XMLName xml.Name `xml:"lfm"`
+	Status  string   `xml:"status,attr"`
+	Inner   any
+}
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"lfm"`
+	Status  string   `xml:"status,attr"`
+	Error   struct {
+		Code    api.ErrorCode `xml:"code,attr"`
+		Message string        `xml:",chardata"`
+	} `xml:"error"`
+}
+
+// writeOK writes a successful response in the given format, with inner as
+// the content of the lfm element of XML responses.
+func writeOK(w http.ResponseWriter, format api.Format, inner any) {
+	if format == api.FormatJSON {
+		xmlBody, err := xml.Marshal(inner)
+		if err != nil {
+			writeError(w, format, http.StatusInternalServerError, api.ErrOperationFailed, err.Error())
+			return
+		}
+
+		body, err := xmlToJSON(xmlBody)
+		if err != nil {
+			writeError(w, format, http.StatusInternalServerError, api.ErrOperationFailed, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(body)
+		return
+	}
+
+	body, err := xml.Marshal(response{Status: "ok", Inner: inner})
+	if err != nil {
+		writeError(w, format, http.StatusInternalServerError, api.ErrOperationFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+// writeError writes an error response in the given format.
+func writeError(
+	w http.ResponseWriter, format api.Format, status int, code api.ErrorCode, message string) {
+
+	if message == "" {
+		message = errorMessage(code)
+	}
+
+	if format == api.FormatJSON {
+		body, _ := json.Marshal(struct {
+			Error   api.ErrorCode `json:"error"`
+			Message string        `json:"message"`
+		}{Error: code, Message: message})
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		w.Write(body)
+		return
+	}
+
+	res := errorResponse{Status: "failed"}
+	res.Error.Code = code
+	res.Error.Message = message
+
+	body, _ := xml.Marshal(res)
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
+
+func errorMessage(code api.ErrorCode) string {
+	switch code {
+	case api.ErrInvalidMethod:
+		return "Invalid Method - No method with that name in this package"
+	case api.ErrAuthenticationFailed:
+		return "Authentication Failed - You do not have permissions to access the service"
+	case api.ErrInvalidParameters:
+		return "Invalid parameters - Your request is missing a required parameter"
+	case api.ErrOperationFailed:
+		return "Operation failed - Something else went wrong"
+	case api.ErrInvalidSessionKey:
+		return "Invalid session key - Please re-authenticate"
+	case api.ErrInvalidAPIKey:
+		return "Invalid API key - You must be granted a valid key by last.fm"
+	case api.ErrServiceOffline:
+		return "Service Offline - This service is temporarily offline. Try again later."
+	case api.ErrInvalidMethodSignature:
+		return "Invalid method signature supplied"
+	case api.ErrUnauthorizedToken:
+		return "Unauthorized Token - This token has not been authorized"
+	case api.ErrServiceUnavailable:
+		return "There was a temporary error processing your request. Please try again"
+	case api.ErrRateLimitExceeded:
+		return "Rate limit exceeded - Your IP has made too many requests in a short period"
+	default:
+		return "Error"
+	}
+}
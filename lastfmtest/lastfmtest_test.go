@@ -0,0 +1,353 @@
+package lastfmtest
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+func TestServer_Auth(t *testing.T) {
+	s := NewServer("", "")
+	defer s.Close()
+
+	s.AddUser("testuser", "testpassword")
+	c := s.Client()
+
+	token, err := c.Auth.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = c.Auth.Session(token)
+	wantErr := &api.LastFMError{Code: api.ErrUnauthorizedToken}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+
+	s.AuthorizeToken(token, "testuser")
+
+	sess, err := c.Auth.Session(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess.Name != "testuser" || sess.Key == "" {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+
+	sess, err = c.Auth.MobileSession("testuser", "testpassword")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess.Name != "testuser" || sess.Key == "" {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+
+	_, err = c.Auth.MobileSession("testuser", "wrongpassword")
+	wantErr = &api.LastFMError{Code: api.ErrAuthenticationFailed}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+
+	c.Secret = "wrongsecret"
+	_, err = c.Auth.Token()
+	wantErr = &api.LastFMError{Code: api.ErrInvalidMethodSignature}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+}
+
+func TestServer_Scrobble(t *testing.T) {
+	s := NewServer("", "")
+	defer s.Close()
+
+	c := s.SessionClient("testuser")
+	now := time.Now().Truncate(time.Second)
+
+	_, err := c.Track.UpdateNowPlaying(lastfm.UpdateNowPlayingParams{
+		Artist: "Artist",
+		Track:  "Playing",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := c.Track.ScrobbleMulti(lastfm.ScrobbleMultiParams{
+		{Artist: "Artist", Track: "First", Time: now.Add(-2 * time.Minute)},
+		{Artist: "Artist", Track: "Second", Time: now.Add(-time.Minute)},
+		{Artist: "Artist", Track: "Old", Time: now.Add(-15 * 24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Accepted != 2 || res.Ignored != 1 {
+		t.Errorf("expected 2 accepted and 1 ignored, got %d and %d", res.Accepted, res.Ignored)
+	}
+	if code := res.Scrobbles[2].Ignored.Code; code != lastfm.TimestampTooOld {
+		t.Errorf("expected ignored code %d, got %d", lastfm.TimestampTooOld, code)
+	}
+
+	if err := c.Track.Love("Artist", "First"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recent, err := c.User.RecentTracksExtended(lastfm.RecentTracksParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"Playing", "Second", "First"}
+	if len(recent.Tracks) != len(want) {
+		t.Fatalf("expected %d tracks, got %d", len(want), len(recent.Tracks))
+	}
+	for i, track := range recent.Tracks {
+		if track.Title != want[i] {
+			t.Errorf("track %d: expected %s, got %s", i, want[i], track.Title)
+		}
+	}
+	if !recent.Tracks[0].NowPlaying {
+		t.Errorf("expected first track to be now playing")
+	}
+	if !recent.Tracks[2].Loved {
+		t.Errorf("expected loved track")
+	}
+	if got := recent.Tracks[1].ScrobbledAt.Unix(); got != now.Add(-time.Minute).Unix() {
+		t.Errorf("expected scrobble time %d, got %d", now.Add(-time.Minute).Unix(), got)
+	}
+
+	if got := len(s.Scrobbles("testuser")); got != 2 {
+		t.Errorf("expected 2 stored scrobbles, got %d", got)
+	}
+}
+
+func TestServer_Charts(t *testing.T) {
+	s := NewServer("", "")
+	defer s.Close()
+
+	c := s.SessionClient("testuser")
+	now := time.Now().Truncate(time.Second)
+
+	_, err := c.Track.ScrobbleMulti(lastfm.ScrobbleMultiParams{
+		{Artist: "Artist", Track: "First", Album: "Album", Time: now.Add(-3 * time.Minute)},
+		{Artist: "Other", Track: "Other", Time: now.Add(-2 * time.Minute)},
+		{Artist: "artist", Track: "first", Album: "album", Time: now.Add(-time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Track.AddTags("Artist", "First", []string{"rock", "indie"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Track.AddTags("Other", "Other", []string{"rock"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	artists, err := c.User.TopArtists(lastfm.UserTopArtistsParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if artists.Total != 2 || artists.Artists[0].Name != "Artist" ||
+		artists.Artists[0].Playcount != 2 || artists.Artists[0].Rank != 1 {
+		t.Errorf("unexpected top artists: %+v", artists)
+	}
+
+	albums, err := c.User.TopAlbums(lastfm.UserTopAlbumsParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if albums.Total != 1 || albums.Albums[0].Title != "Album" ||
+		albums.Albums[0].Artist.Name != "Artist" || albums.Albums[0].Playcount != 2 {
+		t.Errorf("unexpected top albums: %+v", albums)
+	}
+
+	tracks, err := c.User.TopTracks(lastfm.UserTopTracksParams{
+		User:   "testuser",
+		Period: lastfm.PeriodWeek,
+		Limit:  1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tracks.Total != 2 || len(tracks.Tracks) != 1 || tracks.Tracks[0].Title != "First" {
+		t.Errorf("unexpected top tracks: %+v", tracks)
+	}
+
+	library, err := c.Library.Artists(lastfm.LibraryArtistsParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if library.Total != 2 || library.Artists[1].Name != "Other" {
+		t.Errorf("unexpected library artists: %+v", library)
+	}
+
+	tags, err := c.User.TopTags(lastfm.UserTopTagsParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tags.Tags) != 2 || tags.Tags[0].Name != "rock" || tags.Tags[0].Count != 2 {
+		t.Errorf("unexpected top tags: %+v", tags)
+	}
+}
+
+func TestServer_JSONFormat(t *testing.T) {
+	s := NewServer("", "")
+	defer s.Close()
+
+	c := s.SessionClient("testuser")
+	c.SetFormat(api.FormatJSON)
+	now := time.Now().Truncate(time.Second)
+
+	res, err := c.Track.ScrobbleMulti(lastfm.ScrobbleMultiParams{
+		{Artist: "Artist", Track: "First", Time: now.Add(-2 * time.Minute)},
+		{Artist: "Artist", Track: "Old", Time: now.Add(-15 * 24 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Accepted != 1 || res.Ignored != 1 || len(res.Scrobbles) != 2 {
+		t.Errorf("unexpected scrobble result: %+v", res)
+	}
+
+	recent, err := c.User.RecentTracks(lastfm.RecentTracksParams{User: "testuser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recent.Tracks) != 1 || recent.Tracks[0].Title != "First" ||
+		recent.Tracks[0].Artist.Name != "Artist" || recent.Total != 1 {
+		t.Errorf("unexpected recent tracks: %+v", recent)
+	}
+
+	_, err = c.User.Info("unknown")
+	wantErr := &api.LastFMError{Code: api.ErrInvalidParameters}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+}
+
+func TestServer_SetFault(t *testing.T) {
+	cases := []struct {
+		name  string
+		fault Fault
+
+		wantError error
+	}{
+		{
+			name:      "Error code",
+			fault:     Fault{Code: api.ErrServiceOffline, Times: 1},
+			wantError: &api.LastFMError{Code: api.ErrServiceOffline},
+		},
+		{
+			name:      "Status code",
+			fault:     Fault{StatusCode: http.StatusServiceUnavailable, Times: 1},
+			wantError: &api.HTTPError{StatusCode: http.StatusServiceUnavailable},
+		},
+		{
+			name: "Error and status code",
+			fault: Fault{
+				Code:       api.ErrRateLimitExceeded,
+				StatusCode: http.StatusTooManyRequests,
+				Times:      1,
+			},
+			wantError: &api.LastFMError{Code: api.ErrRateLimitExceeded},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := NewServer("", "")
+			defer s.Close()
+
+			s.AddUser("testuser", "")
+			client := s.Client()
+			client.SetRetries(0)
+
+			s.SetFault(api.UserGetInfoMethod, c.fault)
+
+			_, err := client.User.Info("testuser")
+			if !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+
+			if _, err := client.User.Info("testuser"); err != nil {
+				t.Errorf("unexpected error after fault: %v", err)
+			}
+		})
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	s := NewServer("", "")
+	s.AddUser("testuser", "testpassword").RealName = "Test User"
+
+	rec, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec.Client = s.Server.Client()
+
+	c := s.Client()
+	c.Client = rec
+
+	sess, err := c.Auth.MobileSession("testuser", "testpassword")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, err := c.User.Info("testuser")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = rec.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, secret := range []string{s.APIKey, s.Secret, "testpassword", sess.Key} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains unscrubbed value %q", secret)
+		}
+	}
+
+	rep, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c = api.NewClient("otherkey", "othersecret")
+	c.Client = rep
+
+	for range 2 {
+		got, err := c.User.Info("testuser")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Name != want.Name || got.RealName != "Test User" {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	}
+
+	_, err = c.User.Info("otheruser")
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected error %v, got %v", ErrNoInteraction, err)
+	}
+
+	// the same request with another HTTP method isn't matched
+	params := struct {
+		User string `url:"user"`
+	}{User: "testuser"}
+	err = c.Post(nil, api.UserGetInfoMethod, params)
+	if !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected error %v, got %v", ErrNoInteraction, err)
+	}
+}
@@ -0,0 +1,188 @@
+package lastfmtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+// User is a user in the store of a Server.
+type User struct {
+	Name         string
+	Password     string
+	RealName     string
+	Country      string
+	Subscriber   bool
+	RegisteredAt time.Time
+}
+
+// Scrobble is a scrobble accepted by a Server.
+type Scrobble struct {
+	User   string
+	Params lastfm.ScrobbleParams
+}
+
+// LovedTrack is a track loved by a user of a Server.
+type LovedTrack struct {
+	Artist  string
+	Track   string
+	LovedAt time.Time
+}
+
+type store struct {
+	users      map[string]*User
+	tokens     map[string]string // token -> authorized user, or ""
+	sessions   map[string]string // session key -> user
+	scrobbles  []Scrobble
+	nowPlaying map[string]lastfm.UpdateNowPlayingParams
+	loved      map[string][]LovedTrack
+	tags       map[string]map[trackKey][]string
+}
+
+type trackKey struct {
+	artist string
+	track  string
+}
+
+func newTrackKey(artist, track string) trackKey {
+	return trackKey{strings.ToLower(artist), strings.ToLower(track)}
+}
+
+func newStore() store {
+	return store{
+		users:      make(map[string]*User),
+		tokens:     make(map[string]string),
+		sessions:   make(map[string]string),
+		nowPlaying: make(map[string]lastfm.UpdateNowPlayingParams),
+		loved:      make(map[string][]LovedTrack),
+		tags:       make(map[string]map[trackKey][]string),
+	}
+}
+
+// user returns the user with the given name, matched case-insensitively like
+// Last.fm usernames.
+func (st *store) user(name string) (*User, bool) {
+	u, ok := st.users[strings.ToLower(name)]
+	return u, ok
+}
+
+// AddUser adds a user with the given name and password to the store of the
+// server, replacing any existing user with the same name, and returns it. The
+// returned User can be modified to change the user's info.
+func (s *Server) AddUser(name, password string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &User{Name: name, Password: password, RegisteredAt: s.Now()}
+	s.store.users[strings.ToLower(name)] = u
+
+	return u
+}
+
+// AuthorizeToken authorizes the given token on behalf of the given user, as if
+// the user had authorized the application on the auth page. The user is added
+// if it doesn't exist yet.
+func (s *Server) AuthorizeToken(token, user string) {
+	s.ensureUser(user)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store.tokens[token] = user
+}
+
+// SessionKey returns a new session key for the given user, as if the user had
+// completed an auth flow. The user is added if it doesn't exist yet.
+func (s *Server) SessionKey(user string) string {
+	s.ensureUser(user)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.store.newSession(user)
+}
+
+// RevokeSessionKey revokes the given session key, as if the user had revoked
+// the application's access.
+func (s *Server) RevokeSessionKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.store.sessions, key)
+}
+
+// Scrobbles returns the scrobbles accepted for the given user, oldest first.
+func (s *Server) Scrobbles(user string) []lastfm.ScrobbleParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var scrobbles []lastfm.ScrobbleParams
+	for _, sc := range s.store.scrobbles {
+		if strings.EqualFold(sc.User, user) {
+			scrobbles = append(scrobbles, sc.Params)
+		}
+	}
+
+	slices.SortStableFunc(scrobbles, func(a, b lastfm.ScrobbleParams) int {
+		return a.Time.Compare(b.Time)
+	})
+
+	return scrobbles
+}
+
+// NowPlaying returns the track the given user is currently playing, if any.
+func (s *Server) NowPlaying(user string) (lastfm.UpdateNowPlayingParams, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	np, ok := s.store.nowPlaying[strings.ToLower(user)]
+	return np, ok
+}
+
+// LovedTracks returns the tracks loved by the given user, most recent first.
+func (s *Server) LovedTracks(user string) []LovedTrack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.store.loved[strings.ToLower(user)])
+}
+
+// TrackTags returns the tags the given user applied to the given track.
+func (s *Server) TrackTags(user, artist, track string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.store.tags[strings.ToLower(user)][newTrackKey(artist, track)])
+}
+
+func (s *Server) ensureUser(name string) {
+	s.mu.Lock()
+	_, ok := s.store.user(name)
+	s.mu.Unlock()
+
+	if !ok {
+		s.AddUser(name, "")
+	}
+}
+
+func (st *store) newToken() string {
+	token := randomHex()
+	st.tokens[token] = ""
+	return token
+}
+
+func (st *store) newSession(user string) string {
+	key := randomHex()
+	st.sessions[key] = user
+	return key
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
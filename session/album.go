@@ -1,6 +1,8 @@
 package session
 
 import (
+	"context"
+
 	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 )
@@ -17,25 +19,51 @@ func NewAlbum(session *Session) *Album {
 
 // AddTags adds tags to an album for the authenticated user.
 func (a Album) AddTags(artist, album string, tags []string) error {
+	return a.AddTagsContext(context.Background(), artist, album, tags)
+}
+
+// AddTagsContext is like AddTags but uses the given context for the request.
+func (a Album) AddTagsContext(ctx context.Context, artist, album string, tags []string) error {
 	p := lastfm.AlbumAddTagsParams{Artist: artist, Album: album, Tags: tags}
-	return a.session.Post(nil, api.AlbumAddTagsMethod, p)
+	return a.session.PostContext(ctx, nil, api.AlbumAddTagsMethod, p)
 }
 
 // SelfTags returns the tags of an album for the authenticated user by
 // artist and album name.
 func (a Album) SelfTags(params lastfm.AlbumSelfTagsParams) (*lastfm.AlbumTags, error) {
+	return a.SelfTagsContext(context.Background(), params)
+}
+
+// SelfTagsContext is like SelfTags but uses the given context for the request.
+func (a Album) SelfTagsContext(
+	ctx context.Context, params lastfm.AlbumSelfTagsParams) (*lastfm.AlbumTags, error) {
+
 	var res lastfm.AlbumTags
-	return &res, a.session.Get(&res, api.AlbumGetTagsMethod, params)
+	return &res, a.session.GetContext(ctx, &res, api.AlbumGetTagsMethod, params)
 }
 
 // SelfTagsByMBID returns the tags of an album for the authenticated user by MBID.
 func (a Album) SelfTagsByMBID(params lastfm.AlbumSelfTagsMBIDParams) (*lastfm.AlbumTags, error) {
+	return a.SelfTagsByMBIDContext(context.Background(), params)
+}
+
+// SelfTagsByMBIDContext is like SelfTagsByMBID but uses the given context for
+// the request.
+func (a Album) SelfTagsByMBIDContext(
+	ctx context.Context, params lastfm.AlbumSelfTagsMBIDParams) (*lastfm.AlbumTags, error) {
+
 	var res lastfm.AlbumTags
-	return &res, a.session.Get(&res, api.AlbumGetTagsMethod, params)
+	return &res, a.session.GetContext(ctx, &res, api.AlbumGetTagsMethod, params)
 }
 
 // RemoveTag removes a tag from an album for the authenticated user.
 func (a Album) RemoveTag(artist, album, tag string) error {
+	return a.RemoveTagContext(context.Background(), artist, album, tag)
+}
+
+// RemoveTagContext is like RemoveTag but uses the given context for the
+// request.
+func (a Album) RemoveTagContext(ctx context.Context, artist, album, tag string) error {
 	p := lastfm.AlbumRemoveTagParams{Artist: artist, Album: album, Tag: tag}
-	return a.session.Post(nil, api.AlbumRemoveTagMethod, p)
+	return a.session.PostContext(ctx, nil, api.AlbumRemoveTagMethod, p)
 }
@@ -1,6 +1,8 @@
 package session
 
 import (
+	"context"
+
 	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 )
@@ -17,26 +19,52 @@ func NewArtist(session *Session) *Artist {
 
 // AddTags adds tags to an artist for the authenticated user.
 func (a Artist) AddTags(artist string, tags []string) error {
+	return a.AddTagsContext(context.Background(), artist, tags)
+}
+
+// AddTagsContext is like AddTags but uses the given context for the request.
+func (a Artist) AddTagsContext(ctx context.Context, artist string, tags []string) error {
 	p := lastfm.ArtistAddTagsParams{Artist: artist, Tags: tags}
-	return a.session.Post(nil, api.ArtistAddTagsMethod, p)
+	return a.session.PostContext(ctx, nil, api.ArtistAddTagsMethod, p)
 }
 
 // SelfTags returns the tags of an artist for the authenticated user by artist
 // name.
 func (a Artist) SelfTags(params lastfm.ArtistSelfTagsParams) (*lastfm.ArtistTags, error) {
+	return a.SelfTagsContext(context.Background(), params)
+}
+
+// SelfTagsContext is like SelfTags but uses the given context for the request.
+func (a Artist) SelfTagsContext(
+	ctx context.Context, params lastfm.ArtistSelfTagsParams) (*lastfm.ArtistTags, error) {
+
 	var res lastfm.ArtistTags
-	return &res, a.session.Get(&res, api.ArtistGetTagsMethod, params)
+	return &res, a.session.GetContext(ctx, &res, api.ArtistGetTagsMethod, params)
 }
 
 // SelfTagsByMBID returns the tags of an artist for the authenticated user by
 // MBID.
 func (a Artist) SelfTagsByMBID(params lastfm.ArtistSelfTagsMBIDParams) (*lastfm.ArtistTags, error) {
+	return a.SelfTagsByMBIDContext(context.Background(), params)
+}
+
+// SelfTagsByMBIDContext is like SelfTagsByMBID but uses the given context for
+// the request.
+func (a Artist) SelfTagsByMBIDContext(
+	ctx context.Context, params lastfm.ArtistSelfTagsMBIDParams) (*lastfm.ArtistTags, error) {
+
 	var res lastfm.ArtistTags
-	return &res, a.session.Get(&res, api.ArtistGetTagsMethod, params)
+	return &res, a.session.GetContext(ctx, &res, api.ArtistGetTagsMethod, params)
 }
 
 // RemoveTag removes a tag from an artist for the authenticated user.
 func (a Artist) RemoveTag(artist string, tag string) error {
+	return a.RemoveTagContext(context.Background(), artist, tag)
+}
+
+// RemoveTagContext is like RemoveTag but uses the given context for the
+// request.
+func (a Artist) RemoveTagContext(ctx context.Context, artist string, tag string) error {
 	p := lastfm.ArtistRemoveTagParams{Artist: artist, Tag: tag}
-	return a.session.Post(nil, api.ArtistRemoveTagMethod, p)
+	return a.session.PostContext(ctx, nil, api.ArtistRemoveTagMethod, p)
 }
@@ -1,5 +1,7 @@
 package session
 
+import "context"
+
 // Client is a struct that serves as a central point for making authenticated
 // API calls. It embeds a Session and provides fields for interacting with
 // different API routes such as Album, Artist, User, etc.
@@ -51,12 +53,18 @@ func newClient(s *Session) *Client {
 // session key, which is then set in the Client. If the token cannot be used,
 // an error is returned.
 func (c *Client) TokenLogin(token string) error {
-	s, err := c.Auth.Session(token)
+	return c.TokenLoginContext(context.Background(), token)
+}
+
+// TokenLoginContext is like TokenLogin but uses the given context for the
+// request.
+func (c *Client) TokenLoginContext(ctx context.Context, token string) error {
+	s, err := c.Auth.SessionContext(ctx, token)
 	if err != nil {
 		return err
 	}
 
-	c.SessionKey = s.Key
+	c.SetSessionKey(s.Key)
 	return nil
 }
 
@@ -64,11 +72,16 @@ func (c *Client) TokenLogin(token string) error {
 // Calls the AuthGetMobileSession method of the Last.fm API and sets the session
 // key in the Client.
 func (c *Client) Login(username, password string) error {
-	s, err := c.Auth.MobileSession(username, password)
+	return c.LoginContext(context.Background(), username, password)
+}
+
+// LoginContext is like Login but uses the given context for the request.
+func (c *Client) LoginContext(ctx context.Context, username, password string) error {
+	s, err := c.Auth.MobileSessionContext(ctx, username, password)
 	if err != nil {
 		return err
 	}
 
-	c.SessionKey = s.Key
+	c.SetSessionKey(s.Key)
 	return nil
 }
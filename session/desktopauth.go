@@ -0,0 +1,165 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+const (
+	DefaultPollInterval    = 2 * time.Second
+	DefaultMaxPollInterval = 15 * time.Second
+	// TokenLifetime is how long an auth token can be authorized for after it's
+	// fetched.
+	TokenLifetime = 60 * time.Minute
+)
+
+// ErrTokenExpired is returned when the user doesn't authorize the auth token
+// before it expires.
+var ErrTokenExpired = errors.New("auth token expired")
+
+// openURL opens the given URL in the default browser.
+var openURL = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Reap the process once it exits, so it doesn't linger as a zombie.
+	go cmd.Wait()
+	return nil
+}
+
+// DesktopAuth implements the Last.fm desktop authentication flow. It fetches
+// an auth token, sends the user to the authorization URL, and polls the API
+// until the user has authorized the token, so it can be exchanged for a
+// session.
+//
+// https://www.last.fm/api/desktopauth
+type DesktopAuth struct {
+	// Interval is the delay before the first poll. It's doubled after each
+	// poll, up to MaxInterval.
+	Interval time.Duration
+	// MaxInterval caps the delay between polls.
+	MaxInterval time.Duration
+	// Timeout is how long the user has to authorize the token. Defaults to
+	// TokenLifetime.
+	Timeout time.Duration
+	// OpenBrowser, if true, opens the authorization URL in the default browser.
+	// If the browser can't be opened, polling continues regardless, so the URL
+	// should also be shown to the user with OnURL.
+	OpenBrowser bool
+	// OnURL, if set, is called with the authorization URL before polling
+	// starts, e.g. to show it to the user.
+	OnURL func(url string)
+
+	client *Client
+}
+
+// NewDesktopAuth returns a new DesktopAuth that logs in with the given client,
+// with the default poll intervals.
+func NewDesktopAuth(client *Client) *DesktopAuth {
+	return &DesktopAuth{
+		Interval:    DefaultPollInterval,
+		MaxInterval: DefaultMaxPollInterval,
+		Timeout:     TokenLifetime,
+		client:      client,
+	}
+}
+
+// Login fetches an auth token, sends the user to authorize it, and waits until
+// they have. The session key of the resulting session is set in the Client.
+// Returns ErrTokenExpired if the token isn't authorized in time.
+func (d *DesktopAuth) Login() (*lastfm.Session, error) {
+	return d.LoginContext(context.Background())
+}
+
+// LoginContext is like Login but uses the given context for the requests. If
+// the context is cancelled, polling stops and the context error is returned.
+func (d *DesktopAuth) LoginContext(ctx context.Context) (*lastfm.Session, error) {
+	token, err := d.client.Auth.TokenContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	url := d.client.AuthTokenURL(token)
+	if d.OnURL != nil {
+		d.OnURL(url)
+	}
+	if d.OpenBrowser {
+		// The user can still open the URL manually.
+		_ = openURL(url)
+	}
+
+	s, err := d.poll(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	d.client.SetSessionKey(s.Key)
+	return s, nil
+}
+
+// poll requests a session for the token until it has been authorized.
+func (d *DesktopAuth) poll(ctx context.Context, token string) (*lastfm.Session, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = TokenLifetime
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	for {
+		t := time.NewTimer(interval)
+		select {
+		case <-pctx.Done():
+			t.Stop()
+			return nil, d.pollError(ctx, pctx.Err())
+		case <-t.C:
+		}
+
+		s, err := d.client.Auth.SessionContext(pctx, token)
+		switch {
+		case err == nil:
+			return s, nil
+		case errors.Is(err, api.ErrUnauthorizedTokenError):
+			// The user hasn't authorized the token yet.
+		case errors.Is(err, api.ErrItemNotStreamableError):
+			// auth.getSession returns code 15 for expired tokens.
+			return nil, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+		default:
+			return nil, d.pollError(ctx, err)
+		}
+
+		interval = min(interval*2, max(d.MaxInterval, interval))
+	}
+}
+
+// pollError returns ErrTokenExpired if polling timed out, or err otherwise.
+func (d *DesktopAuth) pollError(ctx context.Context, err error) error {
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return ErrTokenExpired
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package session
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/twoscott/gobble-fm/api"
+)
+
+// Manager issues a Client for each user of a service that makes requests on
+// behalf of many users. Every Client shares the same api.API, so they share
+// its HTTP client, rate limiter, cache and other settings.
+//
+// Session keys are loaded lazily from a SessionStore the first time a user's
+// Client is requested. Users whose session key is rejected with
+// api.ErrInvalidSessionKeyError, e.g. because they revoked access, are evicted,
+// and their session key is deleted from the store.
+//
+// A Manager is safe for concurrent use, and so is each Client it issues as
+// long as its session key is only changed with SetSessionKey.
+type Manager struct {
+	// API is the API client shared by every Client.
+	API *api.API
+	// Store is the store session keys are loaded from and saved to.
+	Store SessionStore
+	// OnEvict, if set, is called with the username of each user evicted
+	// because their session key is invalid, e.g. to ask them to log in again.
+	// err is the error deleting their session key from the store, if any.
+	OnEvict func(username string, err error)
+
+	mu      sync.Mutex
+	clients map[string]*Client
+	// gens counts the changes to the stored session key of each user, so
+	// session keys loaded without holding the lock can be checked for changes
+	// made in the meantime.
+	gens map[string]uint64
+}
+
+// NewManager returns a new Manager that issues Clients using the given API
+// client, with session keys from the given store.
+func NewManager(a *api.API, store SessionStore) *Manager {
+	return &Manager{
+		API:     a,
+		Store:   store,
+		clients: make(map[string]*Client),
+		gens:    make(map[string]uint64),
+	}
+}
+
+// Client returns the Client of the given user, loading their session key from
+// the store if needed. Returns ErrSessionNotFound if no session key is stored
+// for the user.
+func (m *Manager) Client(username string) (*Client, error) {
+	for {
+		m.mu.Lock()
+		c, ok := m.clients[username]
+		gen := m.gens[username]
+		m.mu.Unlock()
+
+		if ok {
+			return c, nil
+		}
+
+		// The store is read without holding the lock, so slow stores don't
+		// block the lookups of other users.
+		key, err := m.Store.Load(username)
+
+		m.mu.Lock()
+		if m.gens[username] != gen {
+			// The user was added, removed or evicted in the meantime, so the
+			// loaded session key may be stale.
+			m.mu.Unlock()
+			continue
+		}
+
+		if err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+
+		// Another caller may have loaded the user in the meantime.
+		c, ok = m.clients[username]
+		if !ok {
+			c = m.add(username, key)
+		}
+		m.mu.Unlock()
+
+		return c, nil
+	}
+}
+
+// Add saves the session key of the given user to the store, and returns their
+// Client, replacing any existing Client of the user.
+func (m *Manager) Add(username, key string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gens[username]++
+	if err := m.Store.Save(username, key); err != nil {
+		return nil, err
+	}
+
+	return m.add(username, key), nil
+}
+
+// Remove removes the Client of the given user, and deletes their session key
+// from the store.
+func (m *Manager) Remove(username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gens[username]++
+	delete(m.clients, username)
+	return m.Store.Delete(username)
+}
+
+// Loaded returns the usernames of the users whose Clients are loaded, in
+// sorted order.
+func (m *Manager) Loaded() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	users := make([]string, 0, len(m.clients))
+	for username := range m.clients {
+		users = append(users, username)
+	}
+	slices.Sort(users)
+
+	return users
+}
+
+func (m *Manager) add(username, key string) *Client {
+	s := &Session{API: m.API, SessionKey: key}
+	s.invalidKey = func(key string) { m.evict(username, key) }
+
+	c := newClient(s)
+	m.clients[username] = c
+	return c
+}
+
+// evict removes the Client of the given user and deletes their session key
+// from the store, if their session key is still the given invalid key.
+func (m *Manager) evict(username, key string) {
+	m.mu.Lock()
+
+	c, ok := m.clients[username]
+	if !ok || c.Key() != key {
+		m.mu.Unlock()
+		return
+	}
+
+	m.gens[username]++
+	delete(m.clients, username)
+	err := m.Store.Delete(username)
+	m.mu.Unlock()
+
+	if m.OnEvict != nil {
+		m.OnEvict(username, err)
+	}
+}
@@ -0,0 +1,586 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+const (
+	// DefaultQueueFlushInterval is the default interval at which a started
+	// ScrobbleQueue is flushed in the background.
+	DefaultQueueFlushInterval = time.Minute
+	// DefaultQueueRetryDelay is the default upper bound of the delay before a
+	// started ScrobbleQueue retries a failed flush. The delay grows
+	// exponentially with consecutive failures, up to DefaultQueueMaxRetryDelay.
+	DefaultQueueRetryDelay = 5 * time.Second
+	// DefaultQueueMaxRetryDelay is the default maximum delay before a started
+	// ScrobbleQueue retries a failed flush.
+	DefaultQueueMaxRetryDelay = 10 * time.Minute
+)
+
+// queueCompactThreshold is the number of completed records the log may hold
+// before it is compacted.
+const queueCompactThreshold = 1000
+
+// ScrobbleStatus is the outcome of a scrobble submitted through a
+// ScrobbleQueue.
+type ScrobbleStatus int
+
+const (
+	// ScrobbleAccepted means the scrobble was accepted by the API.
+	ScrobbleAccepted ScrobbleStatus = iota
+	// ScrobbleIgnored means the scrobble was submitted, but ignored by the
+	// API. The reason is reported in the Ignored field of the Scrobble.
+	ScrobbleIgnored
+	// ScrobbleDropped means the scrobble was removed from the queue without
+	// being accepted, either because it became too old, because the API
+	// rejected the request it was submitted in, or because the API didn't
+	// report a result for it.
+	ScrobbleDropped
+)
+
+// ErrNoScrobbleResult is reported for scrobbles the API didn't return a result
+// for.
+var ErrNoScrobbleResult = errors.New("no result for scrobble")
+
+// ScrobbleOutcome reports what happened to a scrobble in a ScrobbleQueue once
+// it leaves the queue.
+type ScrobbleOutcome struct {
+	// Params are the parameters the scrobble was queued with.
+	Params lastfm.ScrobbleParams
+	// Status is the outcome of the scrobble.
+	Status ScrobbleStatus
+	// Scrobble is the result reported by the API for the scrobble, or nil if
+	// the scrobble was dropped.
+	Scrobble *lastfm.Scrobble
+	// Err is the reason the scrobble was dropped, if it was.
+	Err error
+}
+
+// Scrobbler submits batches of scrobbles to the API. It is implemented by
+// Track.
+type Scrobbler interface {
+	ScrobbleMultiContext(
+		ctx context.Context, params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error)
+}
+
+// ScrobbleQueue is a durable queue of scrobbles that are submitted to the API
+// in batches. Scrobbles are written to a write-ahead log file before Add
+// returns, so they survive crashes and restarts, and are only removed from the
+// log once the API has accepted, ignored, or rejected them.
+//
+// Requests that fail with a transient error, such as a network error or
+// Last.fm error 11 or 16, are retried later, and so are requests that fail
+// because of missing or invalid credentials, such as an invalid session key,
+// since they succeed once the user logs in again. Requests the API rejects
+// for any other permanent reason, such as invalid parameters, would fail
+// again, so their scrobbles are dropped. Scrobbles that become older than
+// Last.fm accepts are dropped. The outcome of every scrobble that leaves the
+// queue is reported to OnResult.
+//
+// A ScrobbleQueue is safe for concurrent use.
+type ScrobbleQueue struct {
+	// FlushInterval is the interval at which the queue is flushed after
+	// Start is called. Adding scrobbles also triggers a flush.
+	FlushInterval time.Duration
+	// Backoff determines the delay before a failed background flush is
+	// retried.
+	Backoff api.ExponentialBackoff
+	// OnResult, if set, is called with the outcome of every scrobble that
+	// leaves the queue.
+	OnResult func(outcome ScrobbleOutcome)
+
+	scrobbler Scrobbler
+	path      string
+
+	mu      sync.Mutex
+	wal     *os.File
+	pending []queuedScrobble
+	nextID  uint64
+	records int
+
+	flushMu sync.Mutex
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type queuedScrobble struct {
+	id     uint64
+	params lastfm.ScrobbleParams
+}
+
+// walRecord is a single line of the write-ahead log. An "add" record queues a
+// scrobble, and a "done" record removes the scrobble with the same ID.
+type walRecord struct {
+	Op     string                 `json:"op"`
+	ID     uint64                 `json:"id"`
+	Params *lastfm.ScrobbleParams `json:"params,omitempty"`
+}
+
+const (
+	walOpAdd  = "add"
+	walOpDone = "done"
+)
+
+// OpenScrobbleQueue opens the ScrobbleQueue backed by the write-ahead log at
+// the given path, creating it if it doesn't exist, and restores any scrobbles
+// left in it. Scrobbles are submitted through the given Scrobbler, usually the
+// Track route of an authenticated Client.
+//
+// The queue isn't flushed until Flush or Start is called.
+func OpenScrobbleQueue(scrobbler Scrobbler, path string) (*ScrobbleQueue, error) {
+	q := &ScrobbleQueue{
+		FlushInterval: DefaultQueueFlushInterval,
+		Backoff: api.ExponentialBackoff{
+			BaseDelay: DefaultQueueRetryDelay,
+			MaxDelay:  DefaultQueueMaxRetryDelay,
+		},
+		scrobbler: scrobbler,
+		path:      path,
+		wake:      make(chan struct{}, 1),
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Add queues the given scrobbles. The scrobbles are persisted before Add
+// returns. If any of the scrobbles is invalid according to
+// lastfm.ScrobbleParams.Validate, none of them are queued, and the validation
+// error is returned.
+func (q *ScrobbleQueue) Add(params ...lastfm.ScrobbleParams) error {
+	for _, p := range params {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.wal == nil {
+		return os.ErrClosed
+	}
+
+	items := make([]queuedScrobble, len(params))
+	records := make([]walRecord, len(params))
+	for i := range params {
+		items[i] = queuedScrobble{id: q.nextID + uint64(i), params: params[i]}
+		records[i] = walRecord{Op: walOpAdd, ID: items[i].id, Params: &params[i]}
+	}
+
+	if err := q.appendRecords(records); err != nil {
+		return err
+	}
+
+	q.nextID += uint64(len(params))
+	q.pending = append(q.pending, items...)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Len returns the number of scrobbles in the queue.
+func (q *ScrobbleQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+// Flush submits every queued scrobble to the API in batches of up to
+// lastfm.ScrobbleMultiLimit scrobbles. It stops at the first batch that fails
+// and returns the error, leaving that batch and the rest of the queue to be
+// retried later, unless the API rejected the batch for a reason other than
+// the credentials, in which case its scrobbles are dropped.
+func (q *ScrobbleQueue) Flush(ctx context.Context) error {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	for {
+		batch, err := q.nextBatch()
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		params := make(lastfm.ScrobbleMultiParams, len(batch))
+		for i, item := range batch {
+			params[i] = item.params
+		}
+
+		res, err := q.scrobbler.ScrobbleMultiContext(ctx, params)
+		if err != nil && (!api.IsPermanent(err) || isCredentialError(err)) {
+			return err
+		}
+
+		outcomes := make([]ScrobbleOutcome, len(batch))
+		for i, item := range batch {
+			outcomes[i] = ScrobbleOutcome{Params: item.params}
+
+			switch {
+			case err != nil:
+				outcomes[i].Status = ScrobbleDropped
+				outcomes[i].Err = err
+			case i >= len(res.Scrobbles):
+				outcomes[i].Status = ScrobbleDropped
+				outcomes[i].Err = ErrNoScrobbleResult
+			default:
+				outcomes[i].Scrobble = &res.Scrobbles[i]
+				if res.Scrobbles[i].Ignored.Code != lastfm.ScrobbleNotIgnored {
+					outcomes[i].Status = ScrobbleIgnored
+				}
+			}
+		}
+
+		if err := q.complete(batch, outcomes); err != nil {
+			return err
+		}
+	}
+}
+
+// isCredentialError reports whether err is caused by missing or invalid
+// credentials, such as a missing, invalid or revoked session key, rather than
+// by the scrobbles themselves.
+func isCredentialError(err error) bool {
+	return api.IsAuthError(err) || IsInvalidSession(err) || errors.Is(err, ErrSessionRevoked)
+}
+
+// Start starts flushing the queue in the background, every FlushInterval and
+// whenever scrobbles are added, until the context is done or Close is called.
+// Failed flushes are retried with exponential backoff.
+func (q *ScrobbleQueue) Start(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.stop != nil || q.wal == nil {
+		return
+	}
+
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+
+	go q.run(ctx, q.stop, q.done)
+}
+
+// Close stops flushing the queue in the background and closes the write-ahead
+// log. Scrobbles left in the queue are restored the next time the queue is
+// opened.
+func (q *ScrobbleQueue) Close() error {
+	q.mu.Lock()
+	stop, done := q.stop, q.done
+	q.stop, q.done = nil, nil
+	q.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.wal == nil {
+		return nil
+	}
+
+	err := q.wal.Close()
+	q.wal = nil
+
+	return err
+}
+
+func (q *ScrobbleQueue) run(ctx context.Context, stop, done chan struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var failures uint
+	for {
+		delay := q.FlushInterval
+		wake := q.wake
+
+		if err := q.Flush(ctx); err != nil && ctx.Err() == nil {
+			failures++
+			delay = q.Backoff.Backoff(failures)
+			// don't let new scrobbles cut the backoff short
+			wake = nil
+		} else {
+			failures = 0
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-wake:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// nextBatch drops scrobbles that became invalid, i.e. too old, from the queue,
+// and returns the next batch of scrobbles to submit.
+func (q *ScrobbleQueue) nextBatch() ([]queuedScrobble, error) {
+	q.mu.Lock()
+
+	if q.wal == nil {
+		q.mu.Unlock()
+		return nil, os.ErrClosed
+	}
+
+	now := time.Now()
+
+	var (
+		expired  []queuedScrobble
+		outcomes []ScrobbleOutcome
+	)
+	for _, item := range q.pending {
+		if err := item.params.ValidateAt(now); err != nil {
+			expired = append(expired, item)
+			outcomes = append(outcomes, ScrobbleOutcome{
+				Params: item.params,
+				Status: ScrobbleDropped,
+				Err:    err,
+			})
+		}
+	}
+
+	q.mu.Unlock()
+
+	if len(expired) > 0 {
+		if err := q.complete(expired, outcomes); err != nil {
+			return nil, err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := min(len(q.pending), lastfm.ScrobbleMultiLimit)
+	return append([]queuedScrobble(nil), q.pending[:n]...), nil
+}
+
+// complete removes the given scrobbles from the queue and reports their
+// outcomes.
+func (q *ScrobbleQueue) complete(items []queuedScrobble, outcomes []ScrobbleOutcome) error {
+	q.mu.Lock()
+
+	if q.wal == nil {
+		q.mu.Unlock()
+		return os.ErrClosed
+	}
+
+	done := make(map[uint64]bool, len(items))
+	records := make([]walRecord, len(items))
+	for i, item := range items {
+		done[item.id] = true
+		records[i] = walRecord{Op: walOpDone, ID: item.id}
+	}
+
+	if err := q.appendRecords(records); err != nil {
+		q.mu.Unlock()
+		return err
+	}
+
+	pending := q.pending[:0]
+	for _, item := range q.pending {
+		if !done[item.id] {
+			pending = append(pending, item)
+		}
+	}
+	q.pending = pending
+
+	var err error
+	if q.records-len(q.pending) > queueCompactThreshold {
+		err = q.compact()
+	}
+
+	q.mu.Unlock()
+
+	if q.OnResult != nil {
+		for _, outcome := range outcomes {
+			q.OnResult(outcome)
+		}
+	}
+
+	return err
+}
+
+// load restores the queued scrobbles from the write-ahead log. A malformed
+// record, such as one left partially written by a crash, is skipped.
+func (q *ScrobbleQueue) load() error {
+	f, err := os.Open(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var order []uint64
+	queued := make(map[uint64]lastfm.ScrobbleParams)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+
+		switch rec.Op {
+		case walOpAdd:
+			if rec.Params == nil {
+				continue
+			}
+			if _, ok := queued[rec.ID]; !ok {
+				order = append(order, rec.ID)
+			}
+			queued[rec.ID] = *rec.Params
+		case walOpDone:
+			delete(queued, rec.ID)
+		}
+
+		q.nextID = max(q.nextID, rec.ID+1)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		if params, ok := queued[id]; ok {
+			q.pending = append(q.pending, queuedScrobble{id: id, params: params})
+		}
+	}
+
+	return nil
+}
+
+// compact atomically replaces the write-ahead log with one that only contains
+// the queued scrobbles, and opens it for appending.
+func (q *ScrobbleQueue) compact() error {
+	records := make([]walRecord, len(q.pending))
+	for i := range q.pending {
+		records[i] = walRecord{Op: walOpAdd, ID: q.pending[i].id, Params: &q.pending[i].params}
+	}
+
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	tmp := q.path + ".tmp"
+	if err := writeFileSync(tmp, data); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(q.path))
+
+	wal, err := os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if q.wal != nil {
+		q.wal.Close()
+	}
+	q.wal = wal
+	q.records = len(records)
+
+	return nil
+}
+
+// appendRecords appends the given records to the write-ahead log and syncs it
+// to disk.
+func (q *ScrobbleQueue) appendRecords(records []walRecord) error {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.wal.Write(data); err != nil {
+		return err
+	}
+	if err := q.wal.Sync(); err != nil {
+		return err
+	}
+
+	q.records += len(records)
+	return nil
+}
+
+func encodeRecords(records []walRecord) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeFileSync(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncDir syncs a directory so a rename within it is durable. Errors are
+// ignored, as not every platform supports syncing directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
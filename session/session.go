@@ -15,7 +15,23 @@
 //   - Create and manage authenticated sessions with the Last.fm API.
 //   - Manage Last.fm user authentication and session keys.
 //   - Send authenticated HTTP GET and POST requests.
+//   - Cancel requests or set deadlines using the Context method variants.
 //   - Access different API routes through the Client struct.
+//   - Queue scrobbles durably with ScrobbleQueue, so they are submitted once
+//     the API is reachable again.
+//   - Drive now playing updates and scrobbles from player events with
+//     PlaybackTracker.
+//   - Serve the web authentication callback of a web application with
+//     WebAuthHandler, which protects the flow with a state parameter.
+//   - Log in desktop applications with DesktopAuth, which waits for the user
+//     to authorize the application instead of asking them to confirm it.
+//   - Persist session keys with a SessionStore, such as FileSessionStore,
+//     which encrypts them at rest, and restore them with StoredLogin.
+//   - Make requests on behalf of many users with a Manager, which issues a
+//     Client per user sharing a single API client, and evicts users whose
+//     session key has been revoked.
+//   - Detect revoked session keys, and purge them or authenticate the user
+//     again with OnInvalidSession.
 //
 // Usage:
 //   - Create a new Session or Client instance using the provided constructors.
@@ -24,8 +40,11 @@
 package session
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/google/go-querystring/query"
 	"github.com/twoscott/gobble-fm/api"
@@ -37,9 +56,41 @@ type Session struct {
 	// used to authenticate requests made to the API. Last.fm session keys have
 	// infinite lifetime, so you can store it and reuse it for future requests
 	// without needing to re-authenticate the user.
+	//
+	// Use SetSessionKey and Key to access it while requests may be in flight.
 	SessionKey string
+	// OnInvalidSession, if set, is called when a request fails because the
+	// session key was rejected, e.g. because the user revoked access to the
+	// application. See InvalidSessionFunc.
+	OnInvalidSession InvalidSessionFunc
+
+	// invalidKey, if set, is called with the session key of requests that
+	// fail with api.ErrInvalidSessionKeyError.
+	invalidKey func(key string)
+
+	authMu     sync.Mutex
+	mu         sync.Mutex
+	revokedKey string
+	revokedErr error
 }
 
+// ErrSessionRevoked is returned for requests made with a session key that was
+// previously rejected by the API, without sending them. The error the session
+// key was rejected with is wrapped.
+var ErrSessionRevoked = errors.New("session revoked")
+
+// InvalidSessionFunc is called with the session and error of a request that
+// failed because the session key was rejected, with the Last.fm error code 4,
+// 9 or 14. It may purge the stored session key, or authenticate the user again.
+//
+// If it returns a new session key, the key is set in the session, and the
+// request is retried once with it. Otherwise, the session is marked as
+// revoked, and requests fail with ErrSessionRevoked until a new session key is
+// set. Calls are serialized, so it must not make requests with the session
+// itself. Requests that fail with a session key that has already been replaced
+// are retried with the new key instead.
+type InvalidSessionFunc func(ctx context.Context, s *Session, err error) (key string, rerr error)
+
 // New returns a new instance of Session with the given API key and secret.
 func New(apiKey, secret string) *Session {
 	return NewWithTimeout(apiKey, secret, api.DefaultTimeout)
@@ -59,9 +110,44 @@ func NewWithTimeout(apiKey, secret string, timeout int) *Session {
 // through other means, such as a login process or an authentication flow, or
 // a stored session key from a previous session.
 func (s *Session) SetSessionKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.SessionKey = key
 }
 
+// Key returns the session key for the Last.fm API session. It's safe to call
+// while the session key may be changed, e.g. by OnInvalidSession.
+func (s *Session) Key() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.SessionKey
+}
+
+// SetOnInvalidSession sets the function called when a request fails because
+// the session key was rejected.
+func (s *Session) SetOnInvalidSession(f InvalidSessionFunc) {
+	s.OnInvalidSession = f
+}
+
+// Revoked reports whether the current session key has been rejected by the
+// API.
+func (s *Session) Revoked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.revokedKey != "" && s.revokedKey == s.SessionKey
+}
+
+// IsInvalidSession reports whether err is a Last.fm error caused by an invalid
+// or revoked session key, with the code 4, 9 or 14.
+func IsInvalidSession(err error) bool {
+	return errors.Is(err, api.ErrAuthenticationFailedError) ||
+		errors.Is(err, api.ErrInvalidSessionKeyError) ||
+		errors.Is(err, api.ErrUnauthorizedTokenError)
+}
+
 // CheckCredentials verifies the authentication level required for an API
 // request and ensures the necessary credentials are present. It checks the
 // presence of the Last.fm API session key for requests requiring a session,
@@ -76,7 +162,7 @@ func (s *Session) SetSessionKey(key string) {
 func (s *Session) CheckCredentials(level api.RequestLevel) error {
 	switch level {
 	case api.RequestLevelSession:
-		if s.SessionKey == "" {
+		if s.Key() == "" {
 			return api.NewLastFMError(api.ErrSessionRequired, api.SessionRequiredMessage)
 		}
 		fallthrough
@@ -96,7 +182,15 @@ func (s *Session) CheckCredentials(level api.RequestLevel) error {
 // Returns:
 //   - An error if the request fails or the response cannot be decoded.
 func (s *Session) Get(dest any, method api.APIMethod, params any) error {
-	return s.Request(dest, http.MethodGet, method, params)
+	return s.GetContext(context.Background(), dest, method, params)
+}
+
+// GetContext is like Get but uses the given context for the request. If the
+// context is cancelled, the request and any pending retries are aborted.
+func (s *Session) GetContext(
+	ctx context.Context, dest any, method api.APIMethod, params any) error {
+
+	return s.RequestContext(ctx, dest, http.MethodGet, method, params)
 }
 
 // Post sends an authenticated HTTP POST request to the API with the specified
@@ -111,7 +205,15 @@ func (s *Session) Get(dest any, method api.APIMethod, params any) error {
 // Returns:
 //   - An error if the request fails or the response cannot be unmarshaled.
 func (s *Session) Post(dest any, method api.APIMethod, params any) error {
-	return s.Request(dest, http.MethodPost, method, params)
+	return s.PostContext(context.Background(), dest, method, params)
+}
+
+// PostContext is like Post but uses the given context for the request. If the
+// context is cancelled, the request and any pending retries are aborted.
+func (s *Session) PostContext(
+	ctx context.Context, dest any, method api.APIMethod, params any) error {
+
+	return s.RequestContext(ctx, dest, http.MethodPost, method, params)
 }
 
 // Request sends an authenticated HTTP request to the API using the specified
@@ -126,27 +228,128 @@ func (s *Session) Post(dest any, method api.APIMethod, params any) error {
 // Returns:
 //   - An error if the request fails or the response cannot be unmarshaled.
 func (s *Session) Request(dest any, httpMethod string, method api.APIMethod, params any) error {
-	err := s.CheckCredentials(api.RequestLevelSession)
+	return s.RequestContext(context.Background(), dest, httpMethod, method, params)
+}
+
+// RequestContext is like Request but uses the given context for the request.
+// If the context is cancelled, the request and any pending retries are
+// aborted.
+func (s *Session) RequestContext(
+	ctx context.Context, dest any, httpMethod string, method api.APIMethod, params any) error {
+
+	key := s.Key()
+	if key == "" {
+		return api.NewLastFMError(api.ErrSessionRequired, api.SessionRequiredMessage)
+	}
+
+	err := s.API.CheckCredentials(api.RequestLevelSession)
 	if err != nil {
 		return err
 	}
 
+	if err = s.checkRevoked(key); err != nil {
+		return err
+	}
+
+	err = s.request(ctx, dest, httpMethod, method, params, key)
+	if !IsInvalidSession(err) {
+		return err
+	}
+
+	if s.invalidKey != nil && errors.Is(err, api.ErrInvalidSessionKeyError) {
+		s.invalidKey(key)
+	}
+
+	newKey, rerr := s.invalidSession(ctx, key, err)
+	if rerr != nil || newKey == "" {
+		return err
+	}
+
+	err = s.request(ctx, dest, httpMethod, method, params, newKey)
+	if IsInvalidSession(err) {
+		s.revoke(newKey, err)
+	}
+
+	return err
+}
+
+// request signs and sends a request with the given session key.
+func (s *Session) request(
+	ctx context.Context,
+	dest any, httpMethod string, method api.APIMethod, params any, key string) error {
+
 	p, err := query.Values(params)
 	if err != nil {
 		return err
 	}
 
 	p.Set("api_key", s.APIKey)
-	p.Set("sk", s.SessionKey)
+	p.Set("sk", key)
 	p.Set("method", method.String())
 	p.Set("api_sig", s.Signature(p))
 
 	switch httpMethod {
 	case http.MethodGet:
-		return s.GetURL(dest, api.BuildAPIURL(p))
+		return s.GetURLContext(ctx, dest, s.BuildAPIURL(p))
 	case http.MethodPost:
-		return s.PostBody(dest, api.Endpoint, p.Encode())
+		return s.PostBodyContext(ctx, dest, s.EndpointURL(), p.Encode())
 	default:
 		return errors.New("unsupported HTTP method")
 	}
 }
+
+// checkRevoked returns ErrSessionRevoked if the given session key has been
+// rejected.
+func (s *Session) checkRevoked(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.revokedKey != "" && s.revokedKey == key {
+		return fmt.Errorf("%w: %w", ErrSessionRevoked, s.revokedErr)
+	}
+	return nil
+}
+
+// invalidSession handles the rejection of the given session key, and returns
+// the session key to retry the request with, if any.
+func (s *Session) invalidSession(ctx context.Context, key string, err error) (string, error) {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+
+	s.mu.Lock()
+	current, revoked := s.SessionKey, s.revokedKey == key
+	s.mu.Unlock()
+
+	// The key has already been replaced or revoked by another request.
+	if current != key {
+		return current, nil
+	}
+	if revoked {
+		return "", nil
+	}
+
+	var newKey string
+	var rerr error
+	if s.OnInvalidSession != nil {
+		newKey, rerr = s.OnInvalidSession(ctx, s, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rerr != nil || newKey == "" || newKey == key {
+		s.revokedKey, s.revokedErr = key, err
+		return "", rerr
+	}
+
+	s.SessionKey = newKey
+	return newKey, nil
+}
+
+// revoke marks the given session key as rejected with err.
+func (s *Session) revoke(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokedKey, s.revokedErr = key, err
+}
@@ -1,10 +1,26 @@
 package session
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
 )
 
 func TestSession_CheckCredentials(t *testing.T) {
@@ -54,3 +70,1450 @@ func TestSession_CheckCredentials(t *testing.T) {
 		})
 	}
 }
+
+type mockScrobbler struct {
+	calls        int
+	scrobbleFunc func(params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error)
+}
+
+func (m *mockScrobbler) ScrobbleMultiContext(
+	ctx context.Context, params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error) {
+
+	m.calls++
+	return m.scrobbleFunc(params)
+}
+
+func TestScrobbleQueue(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	cases := []struct {
+		name string
+
+		scrobbles int
+		age       time.Duration
+		ignored   lastfm.ScrobbleIgnoredCode
+		mockError error
+		noResults bool
+
+		wantError   error
+		wantStatus  ScrobbleStatus
+		wantResults int
+		wantCalls   int
+		wantLen     int
+	}{
+		{
+			name:        "Accepted",
+			scrobbles:   2,
+			wantStatus:  ScrobbleAccepted,
+			wantResults: 2,
+			wantCalls:   1,
+		},
+		{
+			name:        "Batched",
+			scrobbles:   120,
+			wantStatus:  ScrobbleAccepted,
+			wantResults: 120,
+			wantCalls:   3,
+		},
+		{
+			name:        "Ignored",
+			scrobbles:   1,
+			ignored:     lastfm.ArtistIgnored,
+			wantStatus:  ScrobbleIgnored,
+			wantResults: 1,
+			wantCalls:   1,
+		},
+		{
+			name:      "Transient error",
+			scrobbles: 2,
+			mockError: &api.LastFMError{Code: api.ErrServiceUnavailable},
+			wantError: &api.LastFMError{Code: api.ErrServiceUnavailable},
+			wantCalls: 1,
+			wantLen:   2,
+		},
+		{
+			name:        "Rejected",
+			scrobbles:   2,
+			mockError:   &api.LastFMError{Code: api.ErrInvalidParameters},
+			wantStatus:  ScrobbleDropped,
+			wantResults: 2,
+			wantCalls:   1,
+		},
+		{
+			name:      "Invalid session key",
+			scrobbles: 2,
+			mockError: &api.LastFMError{Code: api.ErrInvalidSessionKey},
+			wantError: &api.LastFMError{Code: api.ErrInvalidSessionKey},
+			wantCalls: 1,
+			wantLen:   2,
+		},
+		{
+			name:      "Session required",
+			scrobbles: 2,
+			mockError: api.NewLastFMError(api.ErrSessionRequired, api.SessionRequiredMessage),
+			wantError: &api.LastFMError{Code: api.ErrSessionRequired},
+			wantCalls: 1,
+			wantLen:   2,
+		},
+		{
+			name:      "Session revoked",
+			scrobbles: 2,
+			mockError: fmt.Errorf("%w: %w", ErrSessionRevoked, api.ErrInvalidSessionKeyError),
+			wantError: ErrSessionRevoked,
+			wantCalls: 1,
+			wantLen:   2,
+		},
+		{
+			name:      "Network error",
+			scrobbles: 2,
+			mockError: io.ErrUnexpectedEOF,
+			wantError: io.ErrUnexpectedEOF,
+			wantCalls: 1,
+			wantLen:   2,
+		},
+		{
+			name:        "Missing results",
+			scrobbles:   2,
+			noResults:   true,
+			wantStatus:  ScrobbleDropped,
+			wantResults: 2,
+			wantCalls:   1,
+		},
+		{
+			name:        "Too old",
+			scrobbles:   2,
+			age:         15 * 24 * time.Hour,
+			wantStatus:  ScrobbleDropped,
+			wantResults: 2,
+			wantCalls:   0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &mockScrobbler{
+				scrobbleFunc: func(params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error) {
+					if c.mockError != nil {
+						return nil, c.mockError
+					}
+
+					if c.noResults {
+						return &lastfm.ScrobbleMultiResult{}, nil
+					}
+
+					res := &lastfm.ScrobbleMultiResult{Scrobbles: make([]lastfm.Scrobble, len(params))}
+					for i, p := range params {
+						res.Scrobbles[i].Track.Title = p.Track
+						res.Scrobbles[i].Ignored.Code = c.ignored
+					}
+					return res, nil
+				},
+			}
+
+			params := make([]lastfm.ScrobbleParams, c.scrobbles)
+			for i := range params {
+				params[i] = lastfm.ScrobbleParams{
+					Artist: "Artist",
+					Track:  "Track",
+					Time:   now.Add(-c.age - time.Duration(i)*time.Second),
+				}
+			}
+
+			path := filepath.Join(t.TempDir(), "scrobbles.log")
+			if c.age > 0 {
+				// scrobbles that have become too old since they were queued
+				var wal []byte
+				for i := range params {
+					rec := walRecord{Op: walOpAdd, ID: uint64(i), Params: &params[i]}
+					line, _ := json.Marshal(rec)
+					wal = append(append(wal, line...), '\n')
+				}
+				if err := os.WriteFile(path, wal, 0o600); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			q, err := OpenScrobbleQueue(mock, path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var results []ScrobbleOutcome
+			q.OnResult = func(outcome ScrobbleOutcome) {
+				results = append(results, outcome)
+			}
+
+			if c.age == 0 {
+				if err := q.Add(params...); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			err = q.Flush(context.Background())
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+
+			if c.wantCalls != mock.calls {
+				t.Errorf("expected %d calls, got %d", c.wantCalls, mock.calls)
+			}
+			if c.wantResults != len(results) {
+				t.Fatalf("expected %d results, got %d", c.wantResults, len(results))
+			}
+			for i, res := range results {
+				if res.Status != c.wantStatus {
+					t.Errorf("result %d: expected status %d, got %d", i, c.wantStatus, res.Status)
+				}
+				if (res.Status == ScrobbleDropped) != (res.Scrobble == nil) {
+					t.Errorf("result %d: unexpected scrobble %v for status %d", i, res.Scrobble, res.Status)
+				}
+				if c.noResults && !errors.Is(res.Err, ErrNoScrobbleResult) {
+					t.Errorf("result %d: expected error %v, got %v", i, ErrNoScrobbleResult, res.Err)
+				}
+				if !res.Params.Time.Equal(now.Add(-c.age - time.Duration(i)*time.Second)) {
+					t.Errorf("result %d: out of order: %v", i, res.Params.Time)
+				}
+			}
+
+			if err := q.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			q, err = OpenScrobbleQueue(mock, path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer q.Close()
+
+			if c.wantLen != q.Len() {
+				t.Errorf("expected %d scrobbles after reopening, got %d", c.wantLen, q.Len())
+			}
+		})
+	}
+}
+
+type mockHTTPClient struct {
+	doFunc func(req *http.Request) (*http.Response, error)
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return m.doFunc(req)
+}
+
+func TestTrack_ScrobbleMulti(t *testing.T) {
+	cases := []struct {
+		name string
+
+		scrobbles   int
+		concurrency int
+		failBatch   int
+		slowBatch   int
+
+		wantError    error
+		wantRequests int32
+		wantLen      int
+		wantBatches  []bool
+	}{
+		{
+			name:         "Single batch",
+			scrobbles:    50,
+			wantRequests: 1,
+			wantLen:      50,
+		},
+		{
+			name:         "Chunked",
+			scrobbles:    120,
+			wantRequests: 3,
+			wantLen:      120,
+		},
+		{
+			name:         "Concurrent",
+			scrobbles:    230,
+			concurrency:  3,
+			wantRequests: 5,
+			wantLen:      230,
+		},
+		{
+			name:         "Failed batch",
+			scrobbles:    120,
+			failBatch:    2,
+			wantError:    &api.LastFMError{Code: api.ErrInvalidParameters},
+			wantRequests: 2,
+			wantLen:      50,
+			wantBatches:  []bool{true, false, false},
+		},
+		{
+			name:         "Failed concurrent batch",
+			scrobbles:    150,
+			concurrency:  3,
+			failBatch:    2,
+			slowBatch:    1,
+			wantError:    &api.LastFMError{Code: api.ErrInvalidParameters},
+			wantRequests: 3,
+			wantLen:      50,
+			wantBatches:  []bool{true, false, true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests atomic.Int32
+			failed := make(chan struct{})
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					requests.Add(1)
+
+					body, _ := io.ReadAll(req.Body)
+					p, _ := url.ParseQuery(string(body))
+
+					first, _ := strconv.Atoi(p.Get("track[0]"))
+					batch := first/lastfm.ScrobbleMultiLimit + 1
+					if batch == c.slowBatch {
+						// respond once the failed batch has stopped the others
+						<-failed
+						time.Sleep(10 * time.Millisecond)
+					}
+
+					var sb strings.Builder
+					if batch == c.failBatch {
+						defer close(failed)
+						sb.WriteString(`<lfm status="failed"><error code="6">Invalid</error></lfm>`)
+					} else {
+						sb.WriteString(`<lfm status="ok"><scrobbles accepted="0" ignored="0">`)
+						for i := 0; p.Has(fmt.Sprintf("track[%d]", i)); i++ {
+							track := p.Get(fmt.Sprintf("track[%d]", i))
+							fmt.Fprintf(&sb, "<scrobble><track>%s</track></scrobble>", track)
+						}
+						sb.WriteString(`</scrobbles></lfm>`)
+					}
+
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(sb.String())),
+					}, nil
+				},
+			}
+
+			s := &Session{
+				API: &api.API{
+					APIKey:    "testapikey",
+					Secret:    "testsecret",
+					UserAgent: api.DefaultUserAgent,
+					Client:    mockClient,
+				},
+				SessionKey: "testsessionkey",
+			}
+
+			params := make(lastfm.ScrobbleMultiParams, c.scrobbles)
+			for i := range params {
+				params[i] = lastfm.ScrobbleParams{
+					Artist: "Artist",
+					Track:  fmt.Sprint(i),
+					Time:   time.Now(),
+				}
+			}
+
+			res, err := NewTrack(s).ScrobbleMultiConcurrent(params, c.concurrency)
+
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+			if c.wantRequests != requests.Load() {
+				t.Errorf("expected %d requests, got %d", c.wantRequests, requests.Load())
+			}
+			if c.wantBatches != nil {
+				var berr *ScrobbleBatchError
+				if !errors.As(err, &berr) {
+					t.Fatalf("expected batch error, got %v", err)
+				}
+				for i, want := range c.wantBatches {
+					if got := berr.Results[i] != nil; got != want {
+						t.Errorf("batch %d: expected scrobbled %t, got %t", i, want, got)
+					}
+				}
+			}
+			if c.wantLen != len(res.Scrobbles) {
+				t.Fatalf("expected %d scrobbles, got %d", c.wantLen, len(res.Scrobbles))
+			}
+			for i, scrobble := range res.Scrobbles {
+				if scrobble.Track.Title != fmt.Sprint(i) {
+					t.Errorf("scrobble %d: expected track %d, got %s", i, i, scrobble.Track.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestScrobbleQueue_AddInvalid(t *testing.T) {
+	q, err := OpenScrobbleQueue(&mockScrobbler{}, filepath.Join(t.TempDir(), "scrobbles.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer q.Close()
+
+	valid := lastfm.ScrobbleParams{Artist: "Artist", Track: "Track", Time: time.Now()}
+	invalid := lastfm.ScrobbleParams{Track: "Track", Time: time.Now()}
+
+	err = q.Add(valid, invalid)
+
+	wantErr := &lastfm.ScrobbleError{Reason: lastfm.ScrobbleMissingArtist}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("expected empty queue, got %d scrobbles", q.Len())
+	}
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+type fakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward, firing any timers that become due.
+func (c *fakeClock) Advance(d time.Duration) {
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.stopped && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		c.now = next.at
+		next.stopped = true
+		next.f()
+	}
+	c.now = end
+}
+
+type mockTrackScrobbler struct {
+	nowPlaying []lastfm.UpdateNowPlayingParams
+	scrobbles  []lastfm.ScrobbleParams
+	cancelled  int
+}
+
+func (m *mockTrackScrobbler) UpdateNowPlayingContext(
+	ctx context.Context, params lastfm.UpdateNowPlayingParams) (*lastfm.NowPlayingUpdate, error) {
+
+	if err := ctx.Err(); err != nil {
+		m.cancelled++
+		return nil, err
+	}
+
+	m.nowPlaying = append(m.nowPlaying, params)
+	return &lastfm.NowPlayingUpdate{}, nil
+}
+
+func (m *mockTrackScrobbler) ScrobbleContext(
+	ctx context.Context, params lastfm.ScrobbleParams) (*lastfm.ScrobbleResult, error) {
+
+	if err := ctx.Err(); err != nil {
+		m.cancelled++
+		return nil, err
+	}
+
+	m.scrobbles = append(m.scrobbles, params)
+	return &lastfm.ScrobbleResult{}, nil
+}
+
+func TestPlaybackTracker(t *testing.T) {
+	track := lastfm.UpdateNowPlayingParams{
+		Artist:   "Artist",
+		Track:    "Track",
+		Duration: lastfm.DurationMinSec(3, 0),
+	}
+	short := lastfm.UpdateNowPlayingParams{
+		Artist:   "Artist",
+		Track:    "Short",
+		Duration: lastfm.DurationSeconds(20),
+	}
+
+	cases := []struct {
+		name   string
+		events func(p *PlaybackTracker, c *fakeClock)
+
+		wantNowPlaying   int
+		wantScrobbles    int
+		wantScrobbleTime time.Duration
+		wantCancelled    int
+	}{
+		{
+			name: "Threshold reached",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(3 * time.Minute)
+				p.Stop()
+			},
+			wantNowPlaying: 1,
+			wantScrobbles:  1,
+		},
+		{
+			name: "Skipped before threshold",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(89 * time.Second)
+				p.Play(short)
+			},
+			wantNowPlaying: 2,
+			wantScrobbles:  0,
+		},
+		{
+			name: "Pause doesn't count",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(60 * time.Second)
+				p.Pause()
+				c.Advance(10 * time.Minute)
+				p.Resume()
+				c.Advance(29 * time.Second)
+				p.Stop()
+			},
+			wantNowPlaying: 2,
+			wantScrobbles:  0,
+		},
+		{
+			name: "Resumed past threshold",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(60 * time.Second)
+				p.Pause()
+				c.Advance(10 * time.Minute)
+				p.Resume()
+				c.Advance(30 * time.Second)
+			},
+			wantNowPlaying: 2,
+			wantScrobbles:  1,
+		},
+		{
+			name: "Seek forward doesn't count",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(10 * time.Second)
+				p.Seek(170 * time.Second)
+				c.Advance(10 * time.Second)
+				p.Stop()
+			},
+			wantNowPlaying: 1,
+			wantScrobbles:  0,
+		},
+		{
+			name: "Replay after seeking to start",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(3 * time.Minute)
+				p.Seek(0)
+				c.Advance(2 * time.Minute)
+			},
+			wantNowPlaying:   2,
+			wantScrobbles:    2,
+			wantScrobbleTime: 3 * time.Minute,
+		},
+		{
+			name: "Track too short",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(short)
+				c.Advance(20 * time.Second)
+				p.Stop()
+			},
+			wantNowPlaying: 1,
+			wantScrobbles:  0,
+		},
+		{
+			name: "Closed",
+			events: func(p *PlaybackTracker, c *fakeClock) {
+				p.Play(track)
+				c.Advance(60 * time.Second)
+				p.Close()
+				c.Advance(3 * time.Minute)
+				p.Play(short)
+			},
+			wantNowPlaying: 1,
+			wantScrobbles:  0,
+			wantCancelled:  1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			start := time.Now().Add(-time.Hour).Truncate(time.Second)
+			clock := &fakeClock{now: start}
+			mock := &mockTrackScrobbler{}
+
+			p := NewPlaybackTracker(mock, clock)
+			c.events(p, clock)
+
+			if c.wantNowPlaying != len(mock.nowPlaying) {
+				t.Errorf("expected %d now playing updates, got %d", c.wantNowPlaying, len(mock.nowPlaying))
+			}
+			if c.wantCancelled != mock.cancelled {
+				t.Errorf("expected %d cancelled requests, got %d", c.wantCancelled, mock.cancelled)
+			}
+			if c.wantScrobbles != len(mock.scrobbles) {
+				t.Fatalf("expected %d scrobbles, got %d", c.wantScrobbles, len(mock.scrobbles))
+			}
+			if c.wantScrobbles > 0 {
+				last := mock.scrobbles[len(mock.scrobbles)-1]
+				if want := start.Add(c.wantScrobbleTime); !last.Time.Equal(want) {
+					t.Errorf("expected scrobble time %v, got %v", want, last.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestWebAuthHandler(t *testing.T) {
+	cases := []struct {
+		name string
+
+		mockBody   string
+		badState   bool
+		noCookie   bool
+		storeError error
+
+		wantStatus  int
+		wantSession string
+	}{
+		{
+			name:        "Success",
+			mockBody:    `<lfm status="ok"><session><name>testuser</name><key>userkey</key></session></lfm>`,
+			wantStatus:  http.StatusFound,
+			wantSession: "userkey",
+		},
+		{
+			name:       "Unauthorized token",
+			mockBody:   `<lfm status="failed"><error code="14">Unauthorized Token</error></lfm>`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Mismatched state",
+			badState:   true,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Missing state cookie",
+			noCookie:   true,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Store error",
+			mockBody:   `<lfm status="ok"><session><name>testuser</name><key>userkey</key></session></lfm>`,
+			storeError: errors.New("store error"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					requests++
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(c.mockBody)),
+					}, nil
+				},
+			}
+
+			client := newClient(&Session{
+				API: &api.API{
+					APIKey:    "testapikey",
+					Secret:    "testsecret",
+					UserAgent: api.DefaultUserAgent,
+					Client:    mockClient,
+				},
+			})
+
+			var stored *lastfm.Session
+			h := NewWebAuthHandler(client, "https://example.com/callback",
+				func(w http.ResponseWriter, r *http.Request, s *lastfm.Session) error {
+					if c.storeError != nil {
+						return c.storeError
+					}
+					stored = s
+					http.Redirect(w, r, "/", http.StatusFound)
+					return nil
+				})
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/callback", nil))
+
+			res := rec.Result()
+			if res.StatusCode != http.StatusFound {
+				t.Fatalf("expected status %d, got %d", http.StatusFound, res.StatusCode)
+			}
+
+			loc, err := url.Parse(res.Header.Get("Location"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if loc.Query().Get("api_key") != "testapikey" {
+				t.Errorf("expected api_key in auth URL, got %s", loc)
+			}
+			cb, err := url.Parse(loc.Query().Get("cb"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			state := cb.Query().Get("state")
+			if state == "" {
+				t.Fatalf("expected state in callback URL, got %s", cb)
+			}
+
+			cookies := res.Cookies()
+			if len(cookies) != 1 || cookies[0].Value != state || !cookies[0].HttpOnly {
+				t.Fatalf("expected HttpOnly state cookie, got %v", cookies)
+			}
+
+			if c.badState {
+				state = "forged"
+			}
+			req := httptest.NewRequest(http.MethodGet,
+				"/callback?state="+url.QueryEscape(state)+"&token=testtoken", nil)
+			if !c.noCookie {
+				req.AddCookie(cookies[0])
+			}
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected status %d, got %d", c.wantStatus, rec.Code)
+			}
+			if c.wantSession != "" && (stored == nil || stored.Key != c.wantSession) {
+				t.Errorf("expected session key %s, got %v", c.wantSession, stored)
+			}
+			if c.wantSession == "" && stored != nil {
+				t.Errorf("unexpected stored session: %v", stored)
+			}
+			if (c.badState || c.noCookie) && requests != 0 {
+				t.Errorf("expected no requests, got %d", requests)
+			}
+			if client.SessionKey != "" {
+				t.Errorf("expected client session key to be unchanged, got %s", client.SessionKey)
+			}
+		})
+	}
+}
+
+func TestDesktopAuth(t *testing.T) {
+	const (
+		tokenBody   = `<lfm status="ok"><token>testtoken</token></lfm>`
+		sessionBody = `<lfm status="ok"><session><name>testuser</name><key>userkey</key></session></lfm>`
+		pendingBody = `<lfm status="failed"><error code="14">Unauthorized Token</error></lfm>`
+		expiredBody = `<lfm status="failed"><error code="15">This token has expired</error></lfm>`
+		invalidBody = `<lfm status="failed"><error code="4">Invalid authentication token</error></lfm>`
+	)
+
+	cases := []struct {
+		name string
+
+		polls   []string
+		timeout time.Duration
+		cancel  bool
+
+		wantPolls int
+		wantKey   string
+		wantError error
+	}{
+		{
+			name:      "Authorized",
+			polls:     []string{pendingBody, pendingBody, sessionBody},
+			wantPolls: 3,
+			wantKey:   "userkey",
+		},
+		{
+			name:      "Expired token",
+			polls:     []string{pendingBody, expiredBody},
+			wantPolls: 2,
+			wantError: ErrTokenExpired,
+		},
+		{
+			name:      "Timeout",
+			polls:     []string{pendingBody},
+			timeout:   20 * time.Millisecond,
+			wantError: ErrTokenExpired,
+		},
+		{
+			name:      "Cancelled",
+			polls:     []string{pendingBody},
+			cancel:    true,
+			wantError: context.Canceled,
+		},
+		{
+			name:      "Invalid token",
+			polls:     []string{invalidBody},
+			wantPolls: 1,
+			wantError: api.ErrAuthenticationFailedError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var polls int
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					body := tokenBody
+					if req.Method == http.MethodPost {
+						body = c.polls[min(polls, len(c.polls)-1)]
+						polls++
+						if c.cancel {
+							cancel()
+						}
+					}
+
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+					}, nil
+				},
+			}
+
+			client := newClient(&Session{
+				API: &api.API{
+					APIKey:    "testapikey",
+					Secret:    "testsecret",
+					UserAgent: api.DefaultUserAgent,
+					Client:    mockClient,
+				},
+			})
+
+			var authURL string
+			d := NewDesktopAuth(client)
+			d.Interval = time.Millisecond
+			d.MaxInterval = 4 * time.Millisecond
+			if c.timeout > 0 {
+				d.Timeout = c.timeout
+			}
+			d.OnURL = func(url string) { authURL = url }
+
+			s, err := d.LoginContext(ctx)
+
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+			if c.wantPolls > 0 && c.wantPolls != polls {
+				t.Errorf("expected %d polls, got %d", c.wantPolls, polls)
+			}
+			if !strings.Contains(authURL, "token=testtoken") {
+				t.Errorf("expected auth URL with token, got %s", authURL)
+			}
+			if c.wantKey != "" && (s == nil || s.Key != c.wantKey) {
+				t.Errorf("expected session key %s, got %v", c.wantKey, s)
+			}
+			if client.SessionKey != c.wantKey {
+				t.Errorf("expected client session key %q, got %q", c.wantKey, client.SessionKey)
+			}
+		})
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+
+	cases := []struct {
+		name  string
+		store func(t *testing.T) SessionStore
+	}{
+		{
+			name: "Memory",
+			store: func(t *testing.T) SessionStore {
+				return NewMemorySessionStore()
+			},
+		},
+		{
+			name: "File",
+			store: func(t *testing.T) SessionStore {
+				s, err := NewFileSessionStoreIterations(path, "passphrase", 1000)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return s
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.store(t)
+
+			if _, err := s.Load("testuser"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected error %v, got %v", ErrSessionNotFound, err)
+			}
+			if err := s.Save("testuser", "userkey"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Save("otheruser", "otherkey"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if key, err := s.Load("testuser"); err != nil || key != "userkey" {
+				t.Errorf("expected key userkey, got %q (%v)", key, err)
+			}
+			if err := s.Delete("otheruser"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := s.Load("otheruser"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("expected error %v, got %v", ErrSessionNotFound, err)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(data), "userkey") {
+		t.Errorf("expected session key to be encrypted, got %s", data)
+	}
+
+	s, err := NewFileSessionStore(path, "passphrase")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key, err := s.Load("testuser"); err != nil || key != "userkey" {
+		t.Errorf("expected key userkey after reopening, got %q (%v)", key, err)
+	}
+
+	if _, err := NewFileSessionStore(path, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected error %v, got %v", ErrWrongPassphrase, err)
+	}
+
+	// failed writes leave the store unchanged
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Save("newuser", "newkey"); err == nil {
+		t.Errorf("expected error saving session")
+	}
+	if _, err := s.Load("newuser"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected error %v after failed save, got %v", ErrSessionNotFound, err)
+	}
+	if err := s.Delete("testuser"); err == nil {
+		t.Errorf("expected error deleting session")
+	}
+	if key, err := s.Load("testuser"); err != nil || key != "userkey" {
+		t.Errorf("expected key userkey after failed delete, got %q (%v)", key, err)
+	}
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914, section 11.
+	cases := []struct {
+		password   string
+		salt       string
+		iterations int
+
+		want string
+	}{
+		{
+			password:   "passwd",
+			salt:       "salt",
+			iterations: 1,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+				"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			password:   "Password",
+			salt:       "NaCl",
+			iterations: 80000,
+			want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56" +
+				"a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d",
+		},
+	}
+
+	for _, c := range cases {
+		key := pbkdf2SHA256([]byte(c.password), []byte(c.salt), c.iterations, 64)
+		if got := fmt.Sprintf("%x", key); got != c.want {
+			t.Errorf("%d iterations: expected %s, got %s", c.iterations, c.want, got)
+		}
+	}
+}
+
+func TestClient_StoredLogin(t *testing.T) {
+	cases := []struct {
+		name string
+
+		storedKey string
+		loginErr  error
+
+		wantLogins int
+		wantKey    string
+		wantError  error
+	}{
+		{
+			name:      "Restored",
+			storedKey: "storedkey",
+			wantKey:   "storedkey",
+		},
+		{
+			name:       "Logged in",
+			wantLogins: 1,
+			wantKey:    "newkey",
+		},
+		{
+			name:       "Login failed",
+			loginErr:   ErrTokenExpired,
+			wantLogins: 1,
+			wantError:  ErrTokenExpired,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := NewMemorySessionStore()
+			if c.storedKey != "" {
+				store.Save("testuser", c.storedKey)
+			}
+
+			var logins int
+			login := func(ctx context.Context) (*lastfm.Session, error) {
+				logins++
+				if c.loginErr != nil {
+					return nil, c.loginErr
+				}
+				return &lastfm.Session{Name: "testuser", Key: "newkey"}, nil
+			}
+
+			client := NewClient("testapikey", "testsecret")
+			err := client.StoredLogin(store, "testuser", login)
+
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+			if c.wantLogins != logins {
+				t.Errorf("expected %d logins, got %d", c.wantLogins, logins)
+			}
+			if client.SessionKey != c.wantKey {
+				t.Errorf("expected session key %q, got %q", c.wantKey, client.SessionKey)
+			}
+			if key, _ := store.Load("testuser"); key != c.wantKey {
+				t.Errorf("expected stored key %q, got %q", c.wantKey, key)
+			}
+		})
+	}
+}
+
+func TestManager(t *testing.T) {
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			p, _ := url.ParseQuery(string(body))
+
+			res := `<lfm status="ok"></lfm>`
+			if p.Get("sk") == "revokedkey" {
+				res = `<lfm status="failed"><error code="9">Invalid session key</error></lfm>`
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(res)),
+			}, nil
+		},
+	}
+
+	a := &api.API{
+		APIKey:    "testapikey",
+		Secret:    "testsecret",
+		UserAgent: api.DefaultUserAgent,
+		Client:    mockClient,
+	}
+
+	store := NewMemorySessionStore()
+	store.Save("active", "activekey")
+	store.Save("revoked", "revokedkey")
+
+	var evicted []string
+	m := NewManager(a, store)
+	m.OnEvict = func(username string, err error) {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		evicted = append(evicted, username)
+	}
+
+	clients := make([]*Client, 10)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients[i], _ = m.Client("active")
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range clients {
+		if c == nil || c != clients[0] {
+			t.Fatalf("client %d: expected the same client, got %p", i, c)
+		}
+	}
+	if clients[0].API != a || clients[0].SessionKey != "activekey" {
+		t.Errorf("expected client with shared API and session key, got %+v", clients[0].Session)
+	}
+
+	if _, err := m.Client("unknown"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected error %v, got %v", ErrSessionNotFound, err)
+	}
+
+	if err := clients[0].Track.Love("Artist", "Track"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	revoked, err := m.Client("revoked")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = revoked.Track.Love("Artist", "Track")
+	if !errors.Is(err, api.ErrInvalidSessionKeyError) {
+		t.Errorf("expected error %v, got %v", api.ErrInvalidSessionKeyError, err)
+	}
+
+	if !slices.Equal(evicted, []string{"revoked"}) {
+		t.Errorf("expected revoked user to be evicted, got %v", evicted)
+	}
+	if _, err := store.Load("revoked"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected revoked session key to be deleted, got %v", err)
+	}
+	if loaded := m.Loaded(); !slices.Equal(loaded, []string{"active"}) {
+		t.Errorf("expected loaded users [active], got %v", loaded)
+	}
+
+	if _, err := m.Add("revoked", "newkey"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key, _ := store.Load("revoked"); key != "newkey" {
+		t.Errorf("expected stored key newkey, got %q", key)
+	}
+}
+
+// blockingSessionStore is a SessionStore whose first Load blocks until
+// released.
+type blockingSessionStore struct {
+	*MemorySessionStore
+	loading chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (s *blockingSessionStore) Load(username string) (string, error) {
+	key, err := s.MemorySessionStore.Load(username)
+	s.once.Do(func() {
+		close(s.loading)
+		<-s.release
+	})
+	return key, err
+}
+
+func TestManager_RemoveWhileLoading(t *testing.T) {
+	store := &blockingSessionStore{
+		MemorySessionStore: NewMemorySessionStore(),
+		loading:            make(chan struct{}),
+		release:            make(chan struct{}),
+	}
+	store.Save("testuser", "testkey")
+
+	m := NewManager(&api.API{}, store)
+
+	errs := make(chan error)
+	go func() {
+		_, err := m.Client("testuser")
+		errs <- err
+	}()
+
+	<-store.loading
+	if err := m.Remove("testuser"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(store.release)
+
+	if err := <-errs; !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected error %v, got %v", ErrSessionNotFound, err)
+	}
+	if loaded := m.Loaded(); len(loaded) != 0 {
+		t.Errorf("expected removed user not to be loaded, got %v", loaded)
+	}
+}
+
+func TestManager_RemoveConcurrent(t *testing.T) {
+	store := NewMemorySessionStore()
+	m := NewManager(&api.API{}, store)
+
+	var wg sync.WaitGroup
+	for range 50 {
+		store.Save("testuser", "testkey")
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.Client("testuser")
+		}()
+		go func() {
+			defer wg.Done()
+			m.Remove("testuser")
+		}()
+		wg.Wait()
+
+		// once removed, the user must stay removed
+		_, stored := store.Load("testuser")
+		loaded := slices.Contains(m.Loaded(), "testuser")
+		if loaded && errors.Is(stored, ErrSessionNotFound) {
+			t.Fatalf("removed user was loaded again")
+		}
+	}
+}
+
+func TestSession_InvalidSession(t *testing.T) {
+	cases := []struct {
+		name string
+
+		errorCode   int
+		callback    bool
+		newKey      string
+		callbackErr error
+
+		wantRequests  int
+		wantCallbacks int
+		wantKey       string
+		wantError     error
+		wantRevoked   bool
+	}{
+		{
+			name:         "Revoked without callback",
+			errorCode:    9,
+			wantRequests: 1,
+			wantKey:      "oldkey",
+			wantError:    api.ErrInvalidSessionKeyError,
+			wantRevoked:  true,
+		},
+		{
+			name:          "Retried with new key",
+			errorCode:     9,
+			callback:      true,
+			newKey:        "newkey",
+			wantRequests:  2,
+			wantCallbacks: 1,
+			wantKey:       "newkey",
+		},
+		{
+			name:          "New key rejected",
+			errorCode:     4,
+			callback:      true,
+			newKey:        "rejectedkey",
+			wantRequests:  2,
+			wantCallbacks: 1,
+			wantKey:       "rejectedkey",
+			wantError:     api.ErrAuthenticationFailedError,
+			wantRevoked:   true,
+		},
+		{
+			name:          "Callback error",
+			errorCode:     14,
+			callback:      true,
+			callbackErr:   errors.New("login failed"),
+			wantRequests:  1,
+			wantCallbacks: 1,
+			wantKey:       "oldkey",
+			wantError:     api.ErrUnauthorizedTokenError,
+			wantRevoked:   true,
+		},
+		{
+			name:         "Other errors ignored",
+			errorCode:    6,
+			callback:     true,
+			wantRequests: 1,
+			wantKey:      "oldkey",
+			wantError:    api.ErrInvalidParametersError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int
+			mockClient := &mockHTTPClient{
+				doFunc: func(req *http.Request) (*http.Response, error) {
+					requests++
+
+					body, _ := io.ReadAll(req.Body)
+					p, _ := url.ParseQuery(string(body))
+
+					res := `<lfm status="ok"></lfm>`
+					if p.Get("sk") != "newkey" {
+						res = fmt.Sprintf(`<lfm status="failed"><error code="%d">Error</error></lfm>`, c.errorCode)
+					}
+
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(res)),
+					}, nil
+				},
+			}
+
+			s := &Session{
+				API: &api.API{
+					APIKey:    "testapikey",
+					Secret:    "testsecret",
+					UserAgent: api.DefaultUserAgent,
+					Client:    mockClient,
+				},
+				SessionKey: "oldkey",
+			}
+
+			var callbacks int
+			if c.callback {
+				s.SetOnInvalidSession(func(ctx context.Context, s *Session, err error) (string, error) {
+					callbacks++
+					if !IsInvalidSession(err) {
+						t.Errorf("expected invalid session error, got %v", err)
+					}
+					return c.newKey, c.callbackErr
+				})
+			}
+
+			track := NewTrack(s)
+			err := track.Love("Artist", "Track")
+
+			if c.wantError == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if c.wantError != nil && !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v, got %v", c.wantError, err)
+			}
+			if c.wantRequests != requests {
+				t.Errorf("expected %d requests, got %d", c.wantRequests, requests)
+			}
+			if c.wantCallbacks != callbacks {
+				t.Errorf("expected %d callbacks, got %d", c.wantCallbacks, callbacks)
+			}
+			if c.wantKey != s.SessionKey {
+				t.Errorf("expected session key %s, got %s", c.wantKey, s.SessionKey)
+			}
+			if c.wantRevoked != s.Revoked() {
+				t.Errorf("expected revoked %t, got %t", c.wantRevoked, s.Revoked())
+			}
+
+			if !c.wantRevoked {
+				return
+			}
+
+			err = track.Love("Artist", "Track")
+			if !errors.Is(err, ErrSessionRevoked) || !errors.Is(err, c.wantError) {
+				t.Errorf("expected error %v wrapping %v, got %v", ErrSessionRevoked, c.wantError, err)
+			}
+			if c.wantRequests != requests {
+				t.Errorf("expected no more requests, got %d", requests)
+			}
+
+			s.SetSessionKey("newkey")
+			if s.Revoked() {
+				t.Error("expected new session key not to be revoked")
+			}
+			if err := track.Love("Artist", "Track"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSession_InvalidSessionConcurrent(t *testing.T) {
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			body, _ := io.ReadAll(req.Body)
+			p, _ := url.ParseQuery(string(body))
+
+			res := `<lfm status="ok"></lfm>`
+			if p.Get("sk") != "newkey" {
+				res = `<lfm status="failed"><error code="9">Invalid session key</error></lfm>`
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(res)),
+			}, nil
+		},
+	}
+
+	s := &Session{
+		API: &api.API{
+			APIKey:    "testapikey",
+			Secret:    "testsecret",
+			UserAgent: api.DefaultUserAgent,
+			Client:    mockClient,
+		},
+		SessionKey: "oldkey",
+	}
+
+	var callbacks atomic.Int32
+	s.SetOnInvalidSession(func(ctx context.Context, s *Session, err error) (string, error) {
+		callbacks.Add(1)
+		return "newkey", nil
+	})
+
+	track := NewTrack(s)
+
+	errs := make([]error, 8)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = track.Love("Artist", "Track")
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("request %d: unexpected error: %v", i, err)
+		}
+	}
+	if n := callbacks.Load(); n != 1 {
+		t.Errorf("expected 1 callback, got %d", n)
+	}
+	if key := s.Key(); key != "newkey" {
+		t.Errorf("expected session key newkey, got %s", key)
+	}
+	if s.Revoked() {
+		t.Error("expected session not to be revoked")
+	}
+}
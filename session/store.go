@@ -0,0 +1,339 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+// DefaultKeyIterations is the default number of PBKDF2 iterations used to
+// derive the encryption key of a FileSessionStore from its passphrase.
+const DefaultKeyIterations = 600_000
+
+var (
+	// ErrSessionNotFound is returned when no session key is stored for a user.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrWrongPassphrase is returned when a FileSessionStore is opened with a
+	// different passphrase than it was created with.
+	ErrWrongPassphrase = errors.New("wrong session store passphrase")
+)
+
+// SessionStore stores the session keys of users. Last.fm session keys don't
+// expire, so they can be stored and reused instead of authenticating users
+// again.
+type SessionStore interface {
+	// Load returns the session key stored for the user, or ErrSessionNotFound
+	// if there is none.
+	Load(username string) (string, error)
+	// Save stores the session key of the user, replacing any existing key.
+	Save(username, key string) error
+	// Delete deletes the session key stored for the user, if any.
+	Delete(username string) error
+}
+
+// LoginFunc runs a login flow, such as DesktopAuth.LoginContext, and returns
+// the resulting session.
+type LoginFunc func(ctx context.Context) (*lastfm.Session, error)
+
+// RestoreSession sets the session key stored for the user in the Client.
+// Returns ErrSessionNotFound if no session key is stored.
+func (c *Client) RestoreSession(store SessionStore, username string) error {
+	key, err := store.Load(username)
+	if err != nil {
+		return err
+	}
+
+	c.SetSessionKey(key)
+	return nil
+}
+
+// StoredLogin sets the session key stored for the user in the Client. If none
+// is stored, it runs login, and stores the session key of the resulting
+// session for the user.
+func (c *Client) StoredLogin(store SessionStore, username string, login LoginFunc) error {
+	return c.StoredLoginContext(context.Background(), store, username, login)
+}
+
+// StoredLoginContext is like StoredLogin but passes the given context to
+// login.
+func (c *Client) StoredLoginContext(
+	ctx context.Context, store SessionStore, username string, login LoginFunc) error {
+
+	err := c.RestoreSession(store, username)
+	if !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+
+	s, err := login(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.SetSessionKey(s.Key)
+	return store.Save(username, s.Key)
+}
+
+// MemorySessionStore is a SessionStore that keeps session keys in memory. It's
+// intended for tests.
+type MemorySessionStore struct {
+	mu   sync.Mutex
+	keys map[string]string
+}
+
+// NewMemorySessionStore returns a new empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{keys: make(map[string]string)}
+}
+
+// Load returns the session key stored for the user.
+func (s *MemorySessionStore) Load(username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[username]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	return key, nil
+}
+
+// Save stores the session key of the user.
+func (s *MemorySessionStore) Save(username, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[username] = key
+	return nil
+}
+
+// Delete deletes the session key stored for the user.
+func (s *MemorySessionStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, username)
+	return nil
+}
+
+// FileSessionStore is a SessionStore that keeps session keys in a file,
+// encrypted at rest with AES-GCM. The encryption key is derived from a
+// passphrase with PBKDF2-HMAC-SHA256 and a random salt stored in the file.
+// Usernames are stored in plain text.
+type FileSessionStore struct {
+	mu   sync.Mutex
+	path string
+	aead cipher.AEAD
+	file sessionFile
+}
+
+type sessionFile struct {
+	Salt       []byte            `json:"salt"`
+	Iterations int               `json:"iterations"`
+	Check      []byte            `json:"check"`
+	Sessions   map[string][]byte `json:"sessions"`
+}
+
+// checkValue is encrypted in session files to check the passphrase.
+const checkValue = "gobble-fm session store"
+
+// NewFileSessionStore opens the session store in the given file, or creates it
+// if it doesn't exist, using DefaultKeyIterations. Returns ErrWrongPassphrase
+// if the file was created with a different passphrase.
+func NewFileSessionStore(path, passphrase string) (*FileSessionStore, error) {
+	return NewFileSessionStoreIterations(path, passphrase, DefaultKeyIterations)
+}
+
+// NewFileSessionStoreIterations is like NewFileSessionStore but uses the given
+// number of PBKDF2 iterations when creating a new file. Existing files use the
+// number of iterations they were created with.
+func NewFileSessionStoreIterations(
+	path, passphrase string, iterations int) (*FileSessionStore, error) {
+
+	if iterations <= 0 {
+		iterations = DefaultKeyIterations
+	}
+
+	s := &FileSessionStore{path: path}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		s.file = sessionFile{
+			Salt:       make([]byte, 16),
+			Iterations: iterations,
+			Sessions:   make(map[string][]byte),
+		}
+		if _, err = rand.Read(s.file.Salt); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err = json.Unmarshal(data, &s.file); err != nil {
+			return nil, fmt.Errorf("invalid session store %s: %w", path, err)
+		}
+		if s.file.Sessions == nil {
+			s.file.Sessions = make(map[string][]byte)
+		}
+	}
+
+	key := pbkdf2SHA256([]byte(passphrase), s.file.Salt, s.file.Iterations, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+
+	if s.file.Check == nil {
+		if s.file.Check, err = s.seal(checkValue, ""); err != nil {
+			return nil, err
+		}
+		if err = s.write(s.file.Sessions); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
+	if v, err := s.open(s.file.Check, ""); err != nil || v != checkValue {
+		return nil, ErrWrongPassphrase
+	}
+
+	return s, nil
+}
+
+// Load returns the session key stored for the user.
+func (s *FileSessionStore) Load(username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sealed, ok := s.file.Sessions[username]
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+	return s.open(sealed, username)
+}
+
+// Save stores the session key of the user, and writes the file.
+func (s *FileSessionStore) Save(username, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sealed, err := s.seal(key, username)
+	if err != nil {
+		return err
+	}
+
+	sessions := maps.Clone(s.file.Sessions)
+	sessions[username] = sealed
+	return s.write(sessions)
+}
+
+// Delete deletes the session key stored for the user, and writes the file.
+func (s *FileSessionStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.file.Sessions[username]; !ok {
+		return nil
+	}
+
+	sessions := maps.Clone(s.file.Sessions)
+	delete(sessions, username)
+	return s.write(sessions)
+}
+
+// seal encrypts value, bound to the given username, and prepends the nonce.
+func (s *FileSessionStore) seal(value, username string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(value), []byte(username)), nil
+}
+
+// open decrypts a value sealed for the given username.
+func (s *FileSessionStore) open(sealed []byte, username string) (string, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return "", errors.New("invalid sealed session key")
+	}
+
+	value, err := s.aead.Open(nil, sealed[:n], sealed[n:], []byte(username))
+	return string(value), err
+}
+
+// write writes the file atomically with the given sessions, readable only by
+// the current user. The sessions of the store are only replaced once the file
+// has been written, so they always match the file.
+func (s *FileSessionStore) write(sessions map[string][]byte) error {
+	file := s.file
+	file.Sessions = sessions
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	s.file.Sessions = sessions
+	return nil
+}
+
+// pbkdf2SHA256 derives a key of the given length from the password and salt
+// with PBKDF2-HMAC-SHA256, as defined in RFC 8018.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	size := prf.Size()
+
+	var key []byte
+	u := make([]byte, size)
+	t := make([]byte, size)
+
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u = prf.Sum(u[:0])
+		copy(t, u)
+
+		for range iterations - 1 {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
@@ -1,6 +1,11 @@
 package session
 
 import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
 	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 )
@@ -21,61 +26,257 @@ func NewTrack(session *Session) *Track {
 
 // AddTags adds tags to a track for the authenticated user.
 func (t Track) AddTags(artist, track string, tags []string) error {
+	return t.AddTagsContext(context.Background(), artist, track, tags)
+}
+
+// AddTagsContext is like AddTags but uses the given context for the request.
+func (t Track) AddTagsContext(ctx context.Context, artist, track string, tags []string) error {
 	p := lastfm.TrackAddTagsParams{Artist: artist, Track: track, Tags: tags}
-	return t.session.Post(nil, api.TrackAddTagsMethod, p)
+	return t.session.PostContext(ctx, nil, api.TrackAddTagsMethod, p)
 }
 
 // SelfTags returns the tags of a track for the authenticated user by artist and
 // track name.
 func (t Track) SelfTags(params lastfm.TrackSelfTagsParams) (*lastfm.TrackTags, error) {
+	return t.SelfTagsContext(context.Background(), params)
+}
+
+// SelfTagsContext is like SelfTags but uses the given context for the request.
+func (t Track) SelfTagsContext(
+	ctx context.Context, params lastfm.TrackSelfTagsParams) (*lastfm.TrackTags, error) {
+
 	var res lastfm.TrackTags
-	return &res, t.session.Get(&res, api.TrackGetTagsMethod, params)
+	return &res, t.session.GetContext(ctx, &res, api.TrackGetTagsMethod, params)
 }
 
 // SelfTagsByMBID returns the tags of a track for the authenticated user by
 // MBID.
 func (t Track) SelfTagsByMBID(params lastfm.TrackSelfTagsMBIDParams) (*lastfm.TrackTags, error) {
+	return t.SelfTagsByMBIDContext(context.Background(), params)
+}
+
+// SelfTagsByMBIDContext is like SelfTagsByMBID but uses the given context for
+// the request.
+func (t Track) SelfTagsByMBIDContext(
+	ctx context.Context, params lastfm.TrackSelfTagsMBIDParams) (*lastfm.TrackTags, error) {
+
 	var res lastfm.TrackTags
-	return &res, t.session.Get(&res, api.TrackGetTagsMethod, params)
+	return &res, t.session.GetContext(ctx, &res, api.TrackGetTagsMethod, params)
 }
 
 // Love marks a track as loved for the authenticated user.
 func (t Track) Love(artist, track string) error {
+	return t.LoveContext(context.Background(), artist, track)
+}
+
+// LoveContext is like Love but uses the given context for the request.
+func (t Track) LoveContext(ctx context.Context, artist, track string) error {
 	p := lastfm.TrackLoveParams{Artist: artist, Track: track}
-	return t.session.Post(nil, api.TrackLoveMethod, p)
+	return t.session.PostContext(ctx, nil, api.TrackLoveMethod, p)
 }
 
 // RemoveTag removes a tag from a track for the authenticated user.
 func (t Track) RemoveTag(artist, track, tag string) error {
+	return t.RemoveTagContext(context.Background(), artist, track, tag)
+}
+
+// RemoveTagContext is like RemoveTag but uses the given context for the
+// request.
+func (t Track) RemoveTagContext(ctx context.Context, artist, track, tag string) error {
 	p := lastfm.TrackRemoveTagParams{Artist: artist, Track: track, Tag: tag}
-	return t.session.Post(nil, api.TrackRemoveTagMethod, p)
+	return t.session.PostContext(ctx, nil, api.TrackRemoveTagMethod, p)
 }
 
 // Scrobble scrobbles a track for the authenticated user.
 func (t Track) Scrobble(params lastfm.ScrobbleParams) (*lastfm.ScrobbleResult, error) {
+	return t.ScrobbleContext(context.Background(), params)
+}
+
+// ScrobbleContext is like Scrobble but uses the given context for the request.
+func (t Track) ScrobbleContext(
+	ctx context.Context, params lastfm.ScrobbleParams) (*lastfm.ScrobbleResult, error) {
+
 	var res lastfm.ScrobbleResult
-	return &res, t.session.Post(&res, api.TrackScrobbleMethod, params)
+	return &res, t.session.PostContext(ctx, &res, api.TrackScrobbleMethod, params)
 }
 
-// ScrobbleMulti scrobbles multiple tracks for the authenticated user.
+// ScrobbleMulti scrobbles multiple tracks for the authenticated user. If more
+// than lastfm.ScrobbleMultiLimit tracks are given, they are split into batches
+// that are scrobbled one after another, and the results are merged in the
+// order the tracks were given.
+//
+// If a batch fails, no further batches are started, and a *ScrobbleBatchError
+// is returned along with the merged results of the batches before it, so the
+// scrobbles in the result correspond to the first len(res.Scrobbles) tracks.
 func (t Track) ScrobbleMulti(
 	params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error) {
+	return t.ScrobbleMultiContext(context.Background(), params)
+}
+
+// ScrobbleMultiContext is like ScrobbleMulti but uses the given context for the
+// request.
+func (t Track) ScrobbleMultiContext(
+	ctx context.Context, params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error) {
+
+	return t.ScrobbleMultiConcurrentContext(ctx, params, 1)
+}
+
+// ScrobbleMultiConcurrent is like ScrobbleMulti but scrobbles up to
+// concurrency batches at the same time. Requests are still subject to the
+// API's Limiter. The results are merged in the order the tracks were given.
+//
+// If a batch fails, no further batches are started, but batches already
+// started are completed. A *ScrobbleBatchError reporting the outcome of every
+// batch is returned along with the merged results of the batches before the
+// first failed batch. Batches after it may have been scrobbled as well, and
+// are reported by the error, so they aren't sent again.
+func (t Track) ScrobbleMultiConcurrent(
+	params lastfm.ScrobbleMultiParams, concurrency int) (*lastfm.ScrobbleMultiResult, error) {
+	return t.ScrobbleMultiConcurrentContext(context.Background(), params, concurrency)
+}
+
+// ScrobbleMultiConcurrentContext is like ScrobbleMultiConcurrent but uses the
+// given context for the requests.
+func (t Track) ScrobbleMultiConcurrentContext(
+	ctx context.Context,
+	params lastfm.ScrobbleMultiParams,
+	concurrency int) (*lastfm.ScrobbleMultiResult, error) {
+
+	if len(params) <= lastfm.ScrobbleMultiLimit {
+		return t.scrobbleBatch(ctx, params)
+	}
+
+	batches := slices.Collect(slices.Chunk(params, lastfm.ScrobbleMultiLimit))
+	berr := &ScrobbleBatchError{
+		Results: make([]*lastfm.ScrobbleMultiResult, len(batches)),
+		Errs:    make([]error, len(batches)),
+	}
+
+	// failed stops new batches from being started once a batch has failed.
+	// Batches already started aren't cancelled, as the API may have accepted
+	// their scrobbles already.
+	failed := make(chan struct{})
+	var failOnce sync.Once
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+
+loop:
+	for i, batch := range batches {
+		select {
+		case sem <- struct{}{}:
+		case <-failed:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+
+		select {
+		case <-failed:
+			<-sem
+			break loop
+		default:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			res, err := t.scrobbleBatch(ctx, batch)
+			if err != nil {
+				berr.Errs[i] = err
+				failOnce.Do(func() { close(failed) })
+				return
+			}
+			berr.Results[i] = res
+		}()
+	}
+
+	wg.Wait()
+
+	var res lastfm.ScrobbleMultiResult
+	for i := range batches {
+		if berr.Results[i] == nil {
+			if berr.Errs[i] == nil {
+				// the context ended before the batch was started
+				berr.Errs[i] = ctx.Err()
+			}
+			return &res, berr
+		}
+
+		res.Accepted += berr.Results[i].Accepted
+		res.Ignored += berr.Results[i].Ignored
+		res.Scrobbles = append(res.Scrobbles, berr.Results[i].Scrobbles...)
+	}
+
+	return &res, nil
+}
+
+// ScrobbleBatchError is returned when a batch of a multi-scrobble split into
+// batches fails. It reports the outcome of every batch of
+// lastfm.ScrobbleMultiLimit tracks, so the tracks of batches that were
+// scrobbled can be told apart from those that weren't.
+type ScrobbleBatchError struct {
+	// Results are the results of the batches that were scrobbled, or nil for
+	// batches that failed or weren't started.
+	Results []*lastfm.ScrobbleMultiResult
+	// Errs are the errors of the batches that failed, or nil for batches that
+	// were scrobbled or weren't started.
+	Errs []error
+}
+
+// Error returns the error of the first failed batch.
+func (e *ScrobbleBatchError) Error() string {
+	for i, err := range e.Errs {
+		if err != nil {
+			return fmt.Sprintf("scrobble batch %d: %v", i, err)
+		}
+	}
+	return "scrobble batch failed"
+}
+
+// Unwrap returns the errors of the failed batches.
+func (e *ScrobbleBatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (t Track) scrobbleBatch(
+	ctx context.Context, params lastfm.ScrobbleMultiParams) (*lastfm.ScrobbleMultiResult, error) {
 
 	var res lastfm.ScrobbleMultiResult
 	p := multiScrobbleContainer{ScrobbleMultiParams: params}
-	return &res, t.session.Post(&res, api.TrackScrobbleMethod, p)
+	return &res, t.session.PostContext(ctx, &res, api.TrackScrobbleMethod, p)
 }
 
 // Unlove unmarks a track as loved for the authenticated user.
 func (t Track) Unlove(artist, track string) error {
+	return t.UnloveContext(context.Background(), artist, track)
+}
+
+// UnloveContext is like Unlove but uses the given context for the request.
+func (t Track) UnloveContext(ctx context.Context, artist, track string) error {
 	p := lastfm.TrackUnloveParams{Artist: artist, Track: track}
-	return t.session.Post(nil, api.TrackUnloveMethod, p)
+	return t.session.PostContext(ctx, nil, api.TrackUnloveMethod, p)
 }
 
 // UpdateNowPlaying updates the now playing track for the authenticated user.
 func (t Track) UpdateNowPlaying(
 	params lastfm.UpdateNowPlayingParams) (*lastfm.NowPlayingUpdate, error) {
+	return t.UpdateNowPlayingContext(context.Background(), params)
+}
+
+// UpdateNowPlayingContext is like UpdateNowPlaying but uses the given context
+// for the request.
+func (t Track) UpdateNowPlayingContext(
+	ctx context.Context, params lastfm.UpdateNowPlayingParams) (*lastfm.NowPlayingUpdate, error) {
 
 	var res lastfm.NowPlayingUpdate
-	return &res, t.session.Post(&res, api.TrackUpdateNowPlayingMethod, params)
+	return &res, t.session.PostContext(ctx, &res, api.TrackUpdateNowPlayingMethod, params)
 }
@@ -0,0 +1,358 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+// Clock provides the current time and timers to a PlaybackTracker. It allows
+// time to be faked in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after the duration elapses. The
+	// returned Timer can be used to cancel the call.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by a Clock.
+type Timer interface {
+	// Stop prevents the timer from firing. It returns false if the timer has
+	// already fired or been stopped.
+	Stop() bool
+}
+
+// SystemClock is the Clock that uses the system time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// TrackScrobbler sends now playing updates and scrobbles to the API. It is
+// implemented by Track.
+type TrackScrobbler interface {
+	UpdateNowPlayingContext(
+		ctx context.Context, params lastfm.UpdateNowPlayingParams) (*lastfm.NowPlayingUpdate, error)
+	ScrobbleContext(
+		ctx context.Context, params lastfm.ScrobbleParams) (*lastfm.ScrobbleResult, error)
+}
+
+type playbackState int
+
+const (
+	playbackStopped playbackState = iota
+	playbackPlaying
+	playbackPaused
+)
+
+// PlaybackTracker turns player events into now playing updates and scrobbles.
+// It accumulates the time the current track has actually been listened to,
+// excluding pauses and skipped parts, and scrobbles the track once it has been
+// played for long enough, as defined by lastfm.ScrobbleParams.PlayThreshold.
+// Tracks that can't be scrobbled, such as tracks of 30 seconds or shorter, are
+// only reported as now playing.
+//
+// Requests are made synchronously by the goroutine that reports an event, or
+// by the clock's timer goroutine once the threshold is reached, with the
+// context of the tracker. A PlaybackTracker is safe for concurrent use.
+type PlaybackTracker struct {
+	// OnScrobble, if set, is called after the current track is scrobbled.
+	OnScrobble func(params lastfm.ScrobbleParams, res *lastfm.ScrobbleResult)
+	// OnError, if set, is called with the errors of failed now playing
+	// updates and scrobbles.
+	OnError func(err error)
+
+	scrobbler TrackScrobbler
+	clock     Clock
+	ctx       context.Context
+	close     context.CancelFunc
+
+	mu        sync.Mutex
+	state     playbackState
+	track     lastfm.UpdateNowPlayingParams
+	startedAt time.Time
+	resumedAt time.Time
+	played    time.Duration
+	scrobbled bool
+	timer     Timer
+	gen       uint64
+}
+
+// NewPlaybackTracker returns a new PlaybackTracker that sends requests through
+// the given TrackScrobbler, usually the Track route of an authenticated Client.
+// If clock is nil, SystemClock is used.
+func NewPlaybackTracker(scrobbler TrackScrobbler, clock Clock) *PlaybackTracker {
+	return NewPlaybackTrackerContext(context.Background(), scrobbler, clock)
+}
+
+// NewPlaybackTrackerContext is like NewPlaybackTracker but makes requests with
+// the given context. Once the context is cancelled, requests in flight are
+// aborted, and later requests fail.
+func NewPlaybackTrackerContext(
+	ctx context.Context, scrobbler TrackScrobbler, clock Clock) *PlaybackTracker {
+
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &PlaybackTracker{scrobbler: scrobbler, clock: clock, ctx: ctx, close: cancel}
+}
+
+// Play starts tracking the given track from the beginning and updates it as
+// now playing. Call Play when a track starts, including when the player
+// changes to another track. The previous track, if any, is stopped first.
+func (p *PlaybackTracker) Play(track lastfm.UpdateNowPlayingParams) {
+	p.mu.Lock()
+	calls := p.play(track)
+	p.mu.Unlock()
+
+	p.do(calls)
+}
+
+// Pause pauses the current track. Time spent paused doesn't count as listened
+// time.
+func (p *PlaybackTracker) Pause() {
+	p.mu.Lock()
+
+	var calls []func()
+	if p.state == playbackPlaying {
+		calls = p.accumulate()
+		p.state = playbackPaused
+		p.cancel()
+	}
+
+	p.mu.Unlock()
+
+	p.do(calls)
+}
+
+// Resume resumes the current track after it was paused, and updates it as now
+// playing again.
+func (p *PlaybackTracker) Resume() {
+	p.mu.Lock()
+
+	var calls []func()
+	if p.state == playbackPaused {
+		p.state = playbackPlaying
+		p.resumedAt = p.clock.Now()
+		p.schedule()
+		calls = append(calls, p.nowPlaying())
+	}
+
+	p.mu.Unlock()
+
+	p.do(calls)
+}
+
+// Seek reports that the player seeked to the given position in the current
+// track. Listened time is measured as it passes, so skipped parts of the track
+// never count towards the threshold. Seeking back to the start of a playing
+// track that has already been scrobbled starts a new play of the track.
+func (p *PlaybackTracker) Seek(position time.Duration) {
+	p.mu.Lock()
+
+	var calls []func()
+	switch {
+	case p.state == playbackPlaying && position <= 0 && p.scrobbled:
+		calls = p.play(p.track)
+	case p.state == playbackPlaying:
+		calls = p.accumulate()
+	}
+
+	p.mu.Unlock()
+
+	p.do(calls)
+}
+
+// Stop stops tracking the current track, scrobbling it first if it has been
+// played for long enough.
+func (p *PlaybackTracker) Stop() {
+	p.mu.Lock()
+	calls := p.stop()
+	p.mu.Unlock()
+
+	p.do(calls)
+}
+
+// Close stops tracking the current track without scrobbling it, and aborts
+// requests in flight. Later events are still tracked, but their requests
+// fail.
+func (p *PlaybackTracker) Close() {
+	p.mu.Lock()
+	p.state = playbackStopped
+	p.cancel()
+	p.mu.Unlock()
+
+	p.close()
+}
+
+// Played returns the time the current track has been listened to.
+func (p *PlaybackTracker) Played() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.state == playbackPlaying {
+		return p.played + p.clock.Now().Sub(p.resumedAt)
+	}
+	return p.played
+}
+
+// play starts tracking the given track, stopping the previous track first,
+// and returns the calls to make once the lock is released.
+func (p *PlaybackTracker) play(track lastfm.UpdateNowPlayingParams) []func() {
+	calls := p.stop()
+
+	now := p.clock.Now()
+	p.state = playbackPlaying
+	p.track = track
+	p.startedAt = now
+	p.resumedAt = now
+	p.played = 0
+	p.scrobbled = false
+	p.schedule()
+
+	return append(calls, p.nowPlaying())
+}
+
+// stop stops the current track and returns the calls to make once the lock is
+// released.
+func (p *PlaybackTracker) stop() []func() {
+	var calls []func()
+	if p.state == playbackPlaying {
+		calls = p.accumulate()
+	}
+
+	p.state = playbackStopped
+	p.cancel()
+
+	return calls
+}
+
+// accumulate adds the time listened since the track was last resumed to the
+// played time, and returns the scrobble call to make if the threshold has been
+// reached.
+func (p *PlaybackTracker) accumulate() []func() {
+	now := p.clock.Now()
+	p.played += now.Sub(p.resumedAt)
+	p.resumedAt = now
+
+	if call := p.scrobble(now); call != nil {
+		return []func(){call}
+	}
+	return nil
+}
+
+// schedule starts a timer that fires when the current track reaches the
+// scrobble threshold.
+func (p *PlaybackTracker) schedule() {
+	p.cancel()
+	if p.scrobbled {
+		return
+	}
+
+	params := p.scrobbleParams()
+	remaining := params.PlayThreshold().Unwrap() - p.played
+	gen := p.gen
+
+	p.timer = p.clock.AfterFunc(max(remaining, 0), func() {
+		p.mu.Lock()
+
+		var calls []func()
+		if p.gen == gen && p.state == playbackPlaying {
+			calls = p.accumulate()
+		}
+
+		p.mu.Unlock()
+
+		p.do(calls)
+	})
+}
+
+// cancel stops the threshold timer and invalidates any call it has already
+// started.
+func (p *PlaybackTracker) cancel() {
+	p.gen++
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+}
+
+// scrobble returns the call that scrobbles the current track, or nil if the
+// track has already been scrobbled, hasn't been played for long enough, or
+// can't be scrobbled at all.
+func (p *PlaybackTracker) scrobble(now time.Time) func() {
+	if p.scrobbled {
+		return nil
+	}
+
+	params := p.scrobbleParams()
+	if params.ValidatePlayed(lastfm.Duration(p.played)) != nil {
+		return nil
+	}
+
+	p.scrobbled = true
+	if params.ValidateAt(now) != nil {
+		return nil
+	}
+
+	return func() {
+		res, err := p.scrobbler.ScrobbleContext(p.ctx, params)
+		if err != nil {
+			p.onError(err)
+			return
+		}
+		if p.OnScrobble != nil {
+			p.OnScrobble(params, res)
+		}
+	}
+}
+
+// nowPlaying returns the call that updates the current track as now playing.
+func (p *PlaybackTracker) nowPlaying() func() {
+	track := p.track
+
+	return func() {
+		_, err := p.scrobbler.UpdateNowPlayingContext(p.ctx, track)
+		if err != nil {
+			p.onError(err)
+		}
+	}
+}
+
+func (p *PlaybackTracker) scrobbleParams() lastfm.ScrobbleParams {
+	return lastfm.ScrobbleParams{
+		Artist:      p.track.Artist,
+		Track:       p.track.Track,
+		Time:        p.startedAt,
+		Album:       p.track.Album,
+		AlbumArtist: p.track.AlbumArtist,
+		TrackNumber: p.track.TrackNumber,
+		Duration:    p.track.Duration,
+		MBID:        p.track.MBID,
+		Context:     p.track.Context,
+	}
+}
+
+func (p *PlaybackTracker) onError(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	}
+}
+
+func (p *PlaybackTracker) do(calls []func()) {
+	for _, call := range calls {
+		call()
+	}
+}
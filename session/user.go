@@ -1,6 +1,8 @@
 package session
 
 import (
+	"context"
+
 	"github.com/twoscott/gobble-fm/api"
 	"github.com/twoscott/gobble-fm/lastfm"
 )
@@ -17,6 +19,11 @@ func NewUser(session *Session) *User {
 
 // Info returns the information the authenticated user.
 func (u User) SelfInfo() (*lastfm.UserInfo, error) {
+	return u.SelfInfoContext(context.Background())
+}
+
+// SelfInfoContext is like SelfInfo but uses the given context for the request.
+func (u User) SelfInfoContext(ctx context.Context) (*lastfm.UserInfo, error) {
 	var res lastfm.UserInfo
-	return &res, u.session.Get(&res, api.UserGetInfoMethod, nil)
+	return &res, u.session.GetContext(ctx, &res, api.UserGetInfoMethod, nil)
 }
@@ -0,0 +1,218 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/twoscott/gobble-fm/api"
+	"github.com/twoscott/gobble-fm/lastfm"
+)
+
+const (
+	// DefaultStateCookie is the default name of the cookie WebAuthHandler
+	// stores the state parameter in.
+	DefaultStateCookie = "lastfm_auth_state"
+	// DefaultStateMaxAge is the default time users have to authorize the
+	// application before the state parameter expires.
+	DefaultStateMaxAge = 10 * time.Minute
+)
+
+// ErrInvalidState is returned when the state parameter of a web
+// authentication callback is missing or doesn't match the state cookie, e.g.
+// because the callback was forged, or the state has expired.
+var ErrInvalidState = errors.New("invalid or expired auth state")
+
+// StoreSessionFunc is called with the session of a user who completed web
+// authentication. It should store the session, and respond to the request,
+// typically by redirecting the user.
+type StoreSessionFunc func(w http.ResponseWriter, r *http.Request, s *lastfm.Session) error
+
+// WebAuthHandler is an http.Handler that implements the Last.fm web
+// authentication flow. Serve it at the callback URL of the application.
+//
+// Requests without a token query parameter start the flow: the handler stores
+// a random state parameter in a cookie, and redirects the user to the Last.fm
+// authorization page. Once the user has authorized the application, Last.fm
+// redirects them back to the handler with a token, which is exchanged for a
+// session if the state parameter matches, and passed to Store.
+//
+// The session key of the Client isn't changed, so a single handler can be used
+// for every user of a web application.
+//
+// https://www.last.fm/api/webauth
+type WebAuthHandler struct {
+	// CallbackURL is the absolute URL the handler is served at.
+	CallbackURL string
+	// Store is called with the session of each user who completes
+	// authentication. If it returns an error, an error page is served.
+	Store StoreSessionFunc
+	// ErrorHandler, if set, is called to respond to failed authentication
+	// instead of serving the default error page. err is ErrInvalidState, an
+	// error returned by Auth.Session, such as api.ErrUnauthorizedTokenError, or
+	// an error returned by Store.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+	// StateCookie is the name of the cookie the state parameter is stored in.
+	StateCookie string
+	// StateMaxAge is the time users have to authorize the application.
+	StateMaxAge time.Duration
+
+	client *Client
+}
+
+// NewWebAuthHandler returns a new WebAuthHandler that authenticates users with
+// the given client, and passes their sessions to store. callbackURL is the
+// absolute URL the handler is served at.
+func NewWebAuthHandler(client *Client, callbackURL string, store StoreSessionFunc) *WebAuthHandler {
+	return &WebAuthHandler{
+		CallbackURL: callbackURL,
+		Store:       store,
+		StateCookie: DefaultStateCookie,
+		StateMaxAge: DefaultStateMaxAge,
+		client:      client,
+	}
+}
+
+// ServeHTTP starts the authentication flow, or completes it if the request has
+// a token query parameter.
+func (h *WebAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Has("token") {
+		h.callback(w, r)
+		return
+	}
+
+	h.login(w, r)
+}
+
+// login stores a new state parameter in a cookie, and redirects the user to
+// the Last.fm authorization page.
+func (h *WebAuthHandler) login(w http.ResponseWriter, r *http.Request) {
+	state, err := newState()
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	callback, err := url.Parse(h.CallbackURL)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, h.stateCookie(callback, state, int(h.StateMaxAge.Seconds())))
+
+	q := callback.Query()
+	q.Set("state", state)
+	callback.RawQuery = q.Encode()
+
+	http.Redirect(w, r, h.client.AuthCallbackURL(callback.String()), http.StatusFound)
+}
+
+// callback checks the state parameter, and exchanges the token for a session.
+func (h *WebAuthHandler) callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	callback, err := url.Parse(h.CallbackURL)
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	cookie, err := r.Cookie(h.cookieName())
+	state := q.Get("state")
+	if err != nil || state == "" ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+
+		h.fail(w, r, ErrInvalidState)
+		return
+	}
+
+	// The state can only be used once.
+	http.SetCookie(w, h.stateCookie(callback, "", -1))
+
+	s, err := h.client.Auth.SessionContext(r.Context(), q.Get("token"))
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+
+	if err = h.Store(w, r, s); err != nil {
+		h.fail(w, r, err)
+	}
+}
+
+func (h *WebAuthHandler) cookieName() string {
+	if h.StateCookie == "" {
+		return DefaultStateCookie
+	}
+	return h.StateCookie
+}
+
+func (h *WebAuthHandler) stateCookie(callback *url.URL, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     h.cookieName(),
+		Value:    value,
+		Path:     callback.Path,
+		MaxAge:   maxAge,
+		Secure:   callback.Scheme == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// fail responds to failed authentication with the error handler, or the
+// default error page.
+func (h *WebAuthHandler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if h.ErrorHandler != nil {
+		h.ErrorHandler(w, r, err)
+		return
+	}
+
+	status, message := http.StatusInternalServerError, "Something went wrong while logging in."
+	switch {
+	case errors.Is(err, ErrInvalidState):
+		status, message = http.StatusBadRequest, "Your login request has expired or is invalid."
+	case errors.Is(err, api.ErrUnauthorizedTokenError):
+		status, message = http.StatusUnauthorized, "You must authorize the application on Last.fm to log in."
+	case errors.Is(err, api.ErrInvalidParametersError),
+		errors.Is(err, api.ErrAuthenticationFailedError):
+		status, message = http.StatusBadRequest, "Last.fm couldn't verify your login."
+	case api.IsTransient(err):
+		status, message = http.StatusBadGateway, "Last.fm is currently unavailable."
+	}
+
+	retry := strings.SplitN(h.CallbackURL, "?", 2)[0]
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, errorPage, http.StatusText(status), html.EscapeString(message),
+		html.EscapeString(retry))
+}
+
+const errorPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Last.fm login failed</title></head>
+<body>
+<h1>%s</h1>
+<p>%s</p>
+<p><a href="%s">Try again</a></p>
+</body>
+</html>
+`
+
+// newState returns a new random state parameter.
+func newState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}